@@ -0,0 +1,166 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// accessSettings map team slugs and collaborator logins to repository permissions.
+type accessSettings struct {
+	Teams         map[string]string `yaml:"teams"`
+	Collaborators map[string]string `yaml:"collaborators"`
+}
+
+const (
+	defaultTeamPermission         = "read"
+	defaultCollaboratorPermission = "write"
+)
+
+// permissions map permissions shown on github.com to those used by the REST API.
+var permissions = map[string]string{
+	"read":     "pull",
+	"triage":   "triage",
+	"write":    "push",
+	"maintain": "maintain",
+	"admin":    "admin",
+}
+
+// permission returns the REST API permission for a read, triage, write, maintain, or admin permission.
+func permission(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if p, ok := permissions[name]; ok {
+		return p, nil
+	}
+
+	// Also accept REST API permissions.
+	for _, p := range permissions {
+		if name == p {
+			return p, nil
+		}
+	}
+
+	return "", fmt.Errorf("invalid permission %q; expected read, triage, write, maintain, or admin", name)
+}
+
+// accessFlags adds flags to grant teams and collaborators access to new repositories.
+func accessFlags(cmd *cobra.Command, teams, collaborators *[]string) {
+	cmd.Flags().StringSliceVarP(teams, "team", "t", nil, "Grant organization teams access as `slug[:permission]`; read, triage, write, maintain, or admin (default read)")
+	cmd.Flags().StringSliceVar(collaborators, "collaborator", nil, "Grant users access as `login[:permission]`; read, triage, write, maintain, or admin (default write)")
+}
+
+// parseAccessFlags parses teams and collaborators passed to accessFlags.
+func parseAccessFlags(teams, collaborators []string) (access accessSettings, err error) {
+	if access.Teams, err = parseAccess("team", teams, defaultTeamPermission); err != nil {
+		return
+	}
+	access.Collaborators, err = parseAccess("collaborator", collaborators, defaultCollaboratorPermission)
+	return
+}
+
+// parseAccess parses values of the form "name[:permission]" into a map, using defaultPermission if not specified.
+func parseAccess(flag string, values []string, defaultPermission string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	access := make(map[string]string, len(values))
+	for _, value := range values {
+		name, p, ok := strings.Cut(value, ":")
+		if !ok {
+			p = defaultPermission
+		}
+
+		if name == "" {
+			return nil, fmt.Errorf("invalid --%s %q; expected name[:permission]", flag, value)
+		}
+		if _, err := permission(p); err != nil {
+			return nil, fmt.Errorf("invalid --%s %q: %w", flag, value, err)
+		}
+
+		access[name] = p
+	}
+
+	return access, nil
+}
+
+// grantAccess grants teams and collaborators declared by the template manifest and command line access to the current repository.
+func grantAccess(flags accessSettings, opts *applyOptions) error {
+	teams := make(map[string]string)
+	collaborators := make(map[string]string)
+	if opts.manifest != nil && opts.manifest.Access != nil {
+		for _, v := range []struct {
+			dst, src          map[string]string
+			defaultPermission string
+		}{
+			{teams, opts.manifest.Access.Teams, defaultTeamPermission},
+			{collaborators, opts.manifest.Access.Collaborators, defaultCollaboratorPermission},
+		} {
+			for name, p := range v.src {
+				name, err := expand(name, opts)
+				if err != nil {
+					return fmt.Errorf("failed to expand access: %w", err)
+				}
+				if p == "" {
+					p = v.defaultPermission
+				}
+				if name != "" {
+					v.dst[name] = p
+				}
+			}
+		}
+	}
+
+	// Permissions passed on the command line take precedence.
+	for name, p := range flags.Teams {
+		teams[name] = p
+	}
+	for name, p := range flags.Collaborators {
+		collaborators[name] = p
+	}
+
+	if len(teams) == 0 && len(collaborators) == 0 {
+		return nil
+	}
+
+	client, err := opts.restClient(opts.Repo.Host())
+	if err != nil {
+		return err
+	}
+
+	owner, repo := opts.Repo.Owner(), opts.Repo.Name()
+
+	for _, team := range sortedKeys(teams) {
+		p, err := permission(teams[team])
+		if err != nil {
+			return fmt.Errorf("failed to grant team %s access: %w", team, err)
+		}
+
+		opts.logVerbose("granting team %s %s access", team, teams[team])
+		path := fmt.Sprintf("orgs/%s/teams/%s/repos/%s/%s", owner, url.PathEscape(team), owner, repo)
+		if err = send(client, http.MethodPut, path, map[string]string{"permission": p}, nil); err != nil {
+			return fmt.Errorf("failed to grant team %s access: %w", team, err)
+		}
+	}
+
+	for _, login := range sortedKeys(collaborators) {
+		p, err := permission(collaborators[login])
+		if err != nil {
+			return fmt.Errorf("failed to grant %s access: %w", login, err)
+		}
+
+		opts.logVerbose("granting %s %s access", login, collaborators[login])
+		path := fmt.Sprintf("repos/%s/%s/collaborators/%s", owner, repo, url.PathEscape(login))
+		if err = send(client, http.MethodPut, path, map[string]string{"permission": p}, nil); err != nil {
+			return fmt.Errorf("failed to grant %s access: %w", login, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/cli/go-gh/pkg/repository"
+	"github.com/heaths/go-console"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/h2non/gock.v1"
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseAccess(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		values  []string
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name: "none",
+		},
+		{
+			name:   "permissions",
+			values: []string{"engineering:write", "docs", "ops:Admin", "bots:push"},
+			want: map[string]string{
+				"engineering": "write",
+				"docs":        "read",
+				"ops":         "Admin",
+				"bots":        "push",
+			},
+		},
+		{
+			name:    "missing name",
+			values:  []string{":write"},
+			wantErr: `invalid --team ":write"; expected name[:permission]`,
+		},
+		{
+			name:    "invalid permission",
+			values:  []string{"engineering:owner"},
+			wantErr: `invalid --team "engineering:owner": invalid permission "owner"; expected read, triage, write, maintain, or admin`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseAccess("team", tt.values, defaultTeamPermission)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGrantAccess(t *testing.T) {
+	t.Cleanup(gock.Off)
+
+	gock.New("https://api.github.com").
+		Put("/orgs/heaths/teams/engineering/repos/heaths/example").
+		BodyString(`{"permission": "maintain"}`).
+		Reply(204)
+	gock.New("https://api.github.com").
+		Put("/orgs/heaths/teams/example-owners/repos/heaths/example").
+		BodyString(`{"permission": "admin"}`).
+		Reply(204)
+	gock.New("https://api.github.com").
+		Put("/repos/heaths/example/collaborators/octocat").
+		BodyString(`{"permission": "push"}`).
+		Reply(201).
+		JSON(`{"id": 1}`)
+	gock.New("https://api.github.com").
+		Put("/repos/heaths/example/collaborators/hubot").
+		BodyString(`{"permission": "pull"}`).
+		Reply(204)
+
+	var m manifest
+	require.NoError(t, yaml.Unmarshal([]byte(`
+access:
+  teams:
+    engineering: write
+    '{{param "github.repo"}}-owners': admin
+  collaborators:
+    octocat:
+`), &m))
+
+	repo, err := repository.Parse("heaths/example")
+	require.NoError(t, err)
+
+	opts := &applyOptions{
+		GlobalOptions: &GlobalOptions{
+			Console:   console.Fake(),
+			Repo:      repo,
+			authToken: "***",
+			host:      "github.com",
+		},
+		manifest: &m,
+		params: map[string]string{
+			"github.repo": "example",
+		},
+	}
+
+	flags := accessSettings{
+		Teams:         map[string]string{"engineering": "maintain"},
+		Collaborators: map[string]string{"hubot": "read"},
+	}
+
+	err = grantAccess(flags, opts)
+	require.NoError(t, err)
+	assert.True(t, gock.IsDone(), pendingMocks(gock.Pending()))
+}
@@ -0,0 +1,226 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package cmd
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cli/go-gh/pkg/api"
+	"golang.org/x/crypto/nacl/box"
+	"golang.org/x/term"
+)
+
+// actionsSettings are GitHub Actions variables, environments, and secrets created in a new repository.
+type actionsSettings struct {
+	// Variables map Actions variable names to values, which may contain templates.
+	Variables map[string]string `yaml:"variables"`
+
+	// Environments map deployment environment names to optional settings sent as declared.
+	Environments map[string]map[string]any `yaml:"environments"`
+
+	// Secrets are names of required Actions secrets. Values are never stored in the template.
+	Secrets []string `yaml:"secrets"`
+}
+
+// configureActions creates Actions variables, environments, and secrets declared by the template manifest.
+// Secret values are read from secretsFile if specified, or prompted for if stdin is a terminal.
+func configureActions(secretsFile string, opts *applyOptions) error {
+	if opts.manifest == nil || opts.manifest.Actions == nil {
+		return nil
+	}
+	actions := opts.manifest.Actions
+
+	secrets, err := readSecrets(secretsFile, actions.Secrets, opts)
+	if err != nil {
+		return err
+	}
+
+	client, err := opts.restClient(opts.Repo.Host())
+	if err != nil {
+		return err
+	}
+
+	path := fmt.Sprintf("repos/%s/%s", opts.Repo.Owner(), opts.Repo.Name())
+
+	for _, name := range sortedKeys(actions.Variables) {
+		value, err := expand(actions.Variables[name], opts)
+		if err != nil {
+			return fmt.Errorf("failed to expand variable %s: %w", name, err)
+		}
+
+		opts.logVerbose("creating variable %s", name)
+		body := map[string]string{"name": name, "value": value}
+		if err = send(client, http.MethodPost, path+"/actions/variables", body, nil); err != nil {
+			// Update variables created by a previous clone that was resumed.
+			var httpErr api.HTTPError
+			if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusConflict {
+				return fmt.Errorf("failed to create variable %s: %w", name, err)
+			}
+
+			opts.logVerbose("updating existing variable %s", name)
+			if err = send(client, http.MethodPatch, fmt.Sprintf("%s/actions/variables/%s", path, url.PathEscape(name)), body, nil); err != nil {
+				return fmt.Errorf("failed to update variable %s: %w", name, err)
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(actions.Environments) {
+		settings, err := expandValue(actions.Environments[name], opts)
+		if err != nil {
+			return fmt.Errorf("failed to expand environment %s: %w", name, err)
+		}
+
+		body, ok := settings.(map[string]any)
+		if !ok {
+			body = make(map[string]any)
+		}
+
+		opts.logVerbose("creating environment %s", name)
+		if err = send(client, http.MethodPut, fmt.Sprintf("%s/environments/%s", path, url.PathEscape(name)), body, nil); err != nil {
+			return fmt.Errorf("failed to create environment %s: %w", name, err)
+		}
+	}
+
+	if len(actions.Secrets) == 0 {
+		return nil
+	}
+
+	var key struct {
+		KeyID string `json:"key_id"`
+		Key   string `json:"key"`
+	}
+	if err = client.Get(path+"/actions/secrets/public-key", &key); err != nil {
+		return fmt.Errorf("failed to get repository public key: %w", err)
+	}
+
+	publicKey, err := base64.StdEncoding.DecodeString(key.Key)
+	if err != nil || len(publicKey) != 32 {
+		return fmt.Errorf("invalid repository public key %q", key.Key)
+	}
+
+	for _, name := range actions.Secrets {
+		encrypted, err := box.SealAnonymous(nil, []byte(secrets[name]), (*[32]byte)(publicKey), rand.Reader)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt secret %s: %w", name, err)
+		}
+
+		opts.logVerbose("creating secret %s", name)
+		body := map[string]string{
+			"encrypted_value": base64.StdEncoding.EncodeToString(encrypted),
+			"key_id":          key.KeyID,
+		}
+		if err = send(client, http.MethodPut, fmt.Sprintf("%s/actions/secrets/%s", path, url.PathEscape(name)), body, nil); err != nil {
+			return fmt.Errorf("failed to create secret %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// readSecrets returns values for all required secret names from secretsFile or by prompting the user.
+func readSecrets(secretsFile string, names []string, opts *applyOptions) (map[string]string, error) {
+	secrets := make(map[string]string, len(names))
+	if len(names) == 0 {
+		return secrets, nil
+	}
+
+	if secretsFile != "" {
+		f, err := os.Open(secretsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read secrets: %w", err)
+		}
+		defer f.Close()
+
+		if secrets, err = parseSecrets(f); err != nil {
+			return nil, fmt.Errorf("failed to read secrets from %s: %w", secretsFile, err)
+		}
+	}
+
+	var reader *bufio.Reader
+	for _, name := range names {
+		if _, ok := secrets[name]; ok {
+			continue
+		}
+
+		if !opts.isInteractive() {
+			return nil, fmt.Errorf("secret %s is required; pass --secrets-file to specify secret values", name)
+		}
+
+		fmt.Fprintf(opts.Console.Stderr(), "\033[32mValue for secret %s?\033[0m ", name)
+
+		// Mask input when stdin is a terminal; otherwise, read a line as-is.
+		if f, ok := opts.Console.Stdin().(interface{ Fd() uintptr }); ok && term.IsTerminal(int(f.Fd())) {
+			value, err := term.ReadPassword(int(f.Fd()))
+			fmt.Fprintln(opts.Console.Stderr())
+			if err != nil {
+				return nil, fmt.Errorf("failed to read secret %s: %w", name, err)
+			}
+			secrets[name] = string(value)
+			continue
+		}
+
+		if reader == nil {
+			reader = bufio.NewReader(opts.stdin())
+		}
+		value, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to read secret %s: %w", name, err)
+		}
+		secrets[name] = strings.TrimRight(value, "\r\n")
+	}
+
+	return secrets, nil
+}
+
+// parseSecrets reads NAME=value lines, ignoring blank lines and comments. Values may be double-quoted.
+func parseSecrets(r io.Reader) (map[string]string, error) {
+	secrets := make(map[string]string)
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		name, value, ok := strings.Cut(text, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected NAME=value", line)
+		}
+
+		name = strings.TrimSpace(name)
+		value = strings.TrimSpace(value)
+		if strings.HasPrefix(value, `"`) {
+			var err error
+			if value, err = strconv.Unquote(value); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+		}
+
+		secrets[name] = value
+	}
+
+	return secrets, scanner.Err()
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
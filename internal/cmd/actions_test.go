@@ -0,0 +1,252 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package cmd
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cli/go-gh/pkg/repository"
+	"github.com/heaths/go-console"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/nacl/box"
+	"gopkg.in/h2non/gock.v1"
+	"gopkg.in/yaml.v3"
+)
+
+func TestConfigureActions(t *testing.T) {
+	t.Cleanup(gock.Off)
+
+	publicKey, privateKey, err := box.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	// Decrypts the secret sent to make sure it matches the expected value.
+	secret := func(value string) gock.MatchFunc {
+		return func(req *http.Request, _ *gock.Request) (bool, error) {
+			content, err := io.ReadAll(req.Body)
+			if err != nil {
+				return false, err
+			}
+			req.Body = io.NopCloser(bytes.NewReader(content))
+
+			var body struct {
+				EncryptedValue string `json:"encrypted_value"`
+				KeyID          string `json:"key_id"`
+			}
+			if err = json.Unmarshal(content, &body); err != nil {
+				return false, err
+			}
+
+			encrypted, err := base64.StdEncoding.DecodeString(body.EncryptedValue)
+			if err != nil {
+				return false, err
+			}
+
+			decrypted, ok := box.OpenAnonymous(nil, encrypted, publicKey, privateKey)
+			return ok && body.KeyID == "1234" && string(decrypted) == value, nil
+		}
+	}
+
+	gock.New("https://api.github.com").
+		Post("/repos/heaths/example/actions/variables").
+		BodyString(`{"name": "IMAGE", "value": "example-service"}`).
+		Reply(201).
+		JSON(`{}`)
+	gock.New("https://api.github.com").
+		Post("/repos/heaths/example/actions/variables").
+		BodyString(`{"name": "REGION", "value": "westus2"}`).
+		Reply(201).
+		JSON(`{}`)
+	gock.New("https://api.github.com").
+		Put("/repos/heaths/example/environments/production").
+		BodyString(`{"wait_timer": 30}`).
+		Reply(200).
+		JSON(`{}`)
+	gock.New("https://api.github.com").
+		Put("/repos/heaths/example/environments/staging").
+		BodyString(`{}`).
+		Reply(200).
+		JSON(`{}`)
+	gock.New("https://api.github.com").
+		Get("/repos/heaths/example/actions/secrets/public-key").
+		Reply(200).
+		JSON(map[string]string{
+			"key_id": "1234",
+			"key":    base64.StdEncoding.EncodeToString(publicKey[:]),
+		})
+	gock.New("https://api.github.com").
+		Put("/repos/heaths/example/actions/secrets/API_KEY").
+		AddMatcher(secret("from file")).
+		Reply(201).
+		JSON(`{}`)
+	gock.New("https://api.github.com").
+		Put("/repos/heaths/example/actions/secrets/TOKEN").
+		AddMatcher(secret("from prompt")).
+		Reply(201).
+		JSON(`{}`)
+
+	var m manifest
+	require.NoError(t, yaml.Unmarshal([]byte(`
+actions:
+  variables:
+    REGION: westus2
+    IMAGE: '{{param "name" | kebabcase}}'
+  environments:
+    staging:
+    production:
+      wait_timer: 30
+  secrets:
+  - API_KEY
+  - TOKEN
+`), &m))
+
+	secretsFile := filepath.Join(t.TempDir(), "secrets.env")
+	require.NoError(t, os.WriteFile(secretsFile, []byte("# Secrets\nAPI_KEY=\"from file\"\n"), 0o600))
+
+	repo, err := repository.Parse("heaths/example")
+	require.NoError(t, err)
+
+	stdin := bytes.NewBufferString("from prompt\n")
+	stderr := &bytes.Buffer{}
+	opts := &applyOptions{
+		GlobalOptions: &GlobalOptions{
+			Console: console.Fake(
+				console.WithStdin(stdin),
+				console.WithStdinTTY(true),
+				console.WithStderrTTY(true),
+				console.WithStderr(stderr),
+			),
+			Repo:      repo,
+			authToken: "***",
+			host:      "github.com",
+		},
+		manifest: &m,
+		params: map[string]string{
+			"name": "ExampleService",
+		},
+	}
+
+	err = configureActions(secretsFile, opts)
+	require.NoError(t, err)
+	assert.True(t, gock.IsDone(), pendingMocks(gock.Pending()))
+	assert.Contains(t, stderr.String(), "Value for secret TOKEN?")
+	assert.NotContains(t, stderr.String(), "Value for secret API_KEY?")
+}
+
+func TestConfigureActions_existingVariable(t *testing.T) {
+	t.Cleanup(gock.Off)
+
+	// A previous clone created the variable before failing.
+	gock.New("https://api.github.com").
+		Post("/repos/heaths/example/actions/variables").
+		BodyString(`{"name": "IMAGE", "value": "example"}`).
+		Reply(409).
+		JSON(`{"message": "Already exists - Variable already exists"}`)
+	gock.New("https://api.github.com").
+		Patch("/repos/heaths/example/actions/variables/IMAGE").
+		BodyString(`{"name": "IMAGE", "value": "example"}`).
+		Reply(204)
+
+	repo, err := repository.Parse("heaths/example")
+	require.NoError(t, err)
+
+	opts := &applyOptions{
+		GlobalOptions: &GlobalOptions{
+			Console:   console.Fake(),
+			Repo:      repo,
+			authToken: "***",
+			host:      "github.com",
+		},
+		manifest: &manifest{
+			Actions: &actionsSettings{
+				Variables: map[string]string{"IMAGE": "example"},
+			},
+		},
+	}
+
+	err = configureActions("", opts)
+	require.NoError(t, err)
+	assert.True(t, gock.IsDone(), pendingMocks(gock.Pending()))
+}
+
+func TestConfigureActions_noTTY(t *testing.T) {
+	repo, err := repository.Parse("heaths/example")
+	require.NoError(t, err)
+
+	opts := &applyOptions{
+		GlobalOptions: &GlobalOptions{
+			Console:   console.Fake(),
+			Repo:      repo,
+			authToken: "***",
+			host:      "github.com",
+		},
+		manifest: &manifest{
+			Actions: &actionsSettings{
+				Secrets: []string{"TOKEN"},
+			},
+		},
+	}
+
+	// No requests should be sent.
+	err = configureActions("", opts)
+	assert.EqualError(t, err, "secret TOKEN is required; pass --secrets-file to specify secret values")
+}
+
+func TestParseSecrets(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name: "values",
+			content: `
+# comment
+A=1
+ B = two words
+C="quoted\nvalue"
+D=a=b
+`,
+			want: map[string]string{
+				"A": "1",
+				"B": "two words",
+				"C": "quoted\nvalue",
+				"D": "a=b",
+			},
+		},
+		{
+			name:    "missing value",
+			content: "A=1\nB\n",
+			wantErr: "line 2: expected NAME=value",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseSecrets(strings.NewReader(tt.content))
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -4,11 +4,20 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/MakeNowJust/heredoc"
+	"github.com/heaths/gh-template/internal/functions"
 	"github.com/heaths/gh-template/internal/git"
-	"github.com/heaths/go-template"
+	"github.com/heaths/gh-template/internal/template"
+	"github.com/heaths/gh-template/internal/template/processor"
 	"github.com/spf13/cobra"
+	"golang.org/x/exp/slices"
 	"golang.org/x/text/language"
 )
 
@@ -19,17 +28,42 @@ func ApplyCmd(globalOpts *GlobalOptions) *cobra.Command {
 		Use:         "apply",
 		Short:       "Apply project template parameters",
 		Long:        "Apply parameters to an already cloned repository template. Any parameters not passed to --param will prompt the user for a value. These may include a default value used if the user does not enter a value.",
-		Annotations: annotations(),
-		Args:        cobra.NoArgs,
+		Annotations: annotations(variables + sourceVariables),
+		Example: heredoc.Doc(`
+			# Apply parameters to templates in the current repository
+			$ gh template apply
+
+			# Apply a template repository onto the current repository, merging .gitignore
+			$ gh template apply --template heaths/template-golang --strategy skip --strategy .gitignore=merge
+
+			# Undo the last apply
+			$ gh template apply --undo
+		`),
+		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) (err error) {
 			opts.GlobalOptions = globalOpts
 			globalOpts.EnsureRepository() // nolint:errcheck
 
+			if opts.undo {
+				dir, top, err := undoPath(opts.rootDir())
+				if err != nil {
+					return err
+				}
+				return undo(dir, top, opts)
+			}
+
 			return apply(opts)
 		},
 	}
 
 	applyFlags(cmd, opts)
+
+	cmd.Flags().StringVar(&opts.source, "template", "", "Apply a template `repository` onto the current repository")
+	cmd.Flags().StringSliceVar(&opts.strategies, "strategy", nil, "How to merge existing files: skip, overwrite, prompt, or merge; or `[path=]strategy` for matching paths")
+	cmd.Flags().BoolVar(&opts.undo, "undo", false, "Restore files changed by the last apply")
+	cmd.Flags().BoolVarP(&opts.force, "force", "f", false, "Apply even if the working tree has uncommitted changes")
+	cmd.MarkFlagsMutuallyExclusive("undo", "template")
+
 	return cmd
 }
 
@@ -60,6 +94,12 @@ func applyFlags(c *cobra.Command, opts *applyOptions) {
 			return
 		}
 
+		if opts.license != "" {
+			if opts.license, err = functions.LicenseID(opts.license); err != nil {
+				return
+			}
+		}
+
 		if opts.params == nil {
 			opts.params = make(map[string]string)
 		}
@@ -67,28 +107,59 @@ func applyFlags(c *cobra.Command, opts *applyOptions) {
 		return
 	}
 
+	c.Flags().StringSliceVar(&opts.environment, "env", nil, "Environment variable `names` templates may reference as env.NAME")
 	c.Flags().StringSliceVar(&delims, "delims", nil, "`left,right` delimiters to open and close template expressions")
 	c.Flags().StringSliceVarP(&opts.exclusions, "exclude", "x", nil, "Any `paths` to exclude using case-insensitive comparisons")
 	c.Flags().StringVarP(&lang, "language", "l", "en", "BCP-47 language for some template functions")
+	c.Flags().StringVar(&opts.license, "license", "", "SPDX license `identifier` used by the license and spdx functions e.g., MIT")
 	c.Flags().StringToStringVarP(&opts.params, "param", "p", nil, "Parameters to apply to project template as `name=value`")
 }
 
 type applyOptions struct {
 	*GlobalOptions
 
-	leftDelim  string
-	rightDelim string
-	exclusions []string
-	language   language.Tag
-	params     map[string]string
+	leftDelim   string
+	rightDelim  string
+	exclusions  []string
+	environment []string
+	language    language.Tag
+	license     string
+	params      map[string]string
+	manifest    *manifest
+
+	// root is the directory to which templates are applied, or the current directory if empty.
+	root string
+
+	// origin is the template repository the root was created from, if any, which dependencies cannot include again.
+	origin string
+
+	// Template repository to apply onto the current repository.
+	source     string
+	strategies []string
+
+	undo  bool
+	force bool
+
+	// addLicense adds a LICENSE file for the license if the templates do not contain one.
+	addLicense bool
+}
+
+// rootDir returns the directory to which templates are applied.
+func (opts *applyOptions) rootDir() string {
+	if opts.root == "" {
+		return "."
+	}
+	return opts.root
 }
 
 func apply(opts *applyOptions) error {
-	if name, email, err := git.User(); err == nil {
-		opts.params["git.name"] = name
-		opts.params["git.email"] = email
-	} else if opts.Verbose && opts.Log != nil {
-		opts.Log.Printf("failed to get git config: %v", err)
+	if user, err := git.User(opts.rootDir()); err == nil {
+		opts.params["git.name"] = user.Name
+		opts.params["git.email"] = user.Email
+		opts.logVerbose("using git user.name from %s", user.NameSource)
+		opts.logVerbose("using git user.email from %s", user.EmailSource)
+	} else {
+		opts.logVerbose("failed to get git config: %v", err)
 	}
 
 	if opts.Repo != nil {
@@ -97,10 +168,121 @@ func apply(opts *applyOptions) error {
 		opts.params["github.repo"] = opts.Repo.Name()
 	}
 
-	return template.Apply(".", opts.params,
-		template.WithExclusions(opts.exclusions),
+	if !opts.force {
+		if err := ensureClean(opts.rootDir()); err != nil {
+			return err
+		}
+	}
+
+	// Render into a staging copy so the working tree is only changed if all templates succeed.
+	return transact(opts.rootDir(), opts, func(staging string) (err error) {
+		if opts.source != "" {
+			err = applyTemplate(staging, opts)
+		} else {
+			err = applyDir(staging, opts)
+		}
+		if err != nil || !opts.addLicense {
+			return
+		}
+
+		return writeLicense(staging, opts)
+	})
+}
+
+// ensureClean returns an error listing any modified or untracked files in the repository containing root.
+func ensureClean(root string) error {
+	files, err := git.Status(root)
+	if err != nil {
+		if errors.Is(err, git.ErrNotRepository) {
+			return nil
+		}
+		return fmt.Errorf("failed to get working tree status: %w", err)
+	}
+
+	if len(files) == 0 {
+		return nil
+	}
+
+	lines := make([]string, len(files))
+	for i, file := range files {
+		lines[i] = file.String()
+	}
+
+	return fmt.Errorf("working tree has uncommitted changes; commit or stash them, or pass --force to apply anyway:\n  %s", strings.Join(lines, "\n  "))
+}
+
+// applyDir applies templates to all files under root.
+func applyDir(root string, opts *applyOptions) error {
+	if opts.manifest == nil {
+		var err error
+		if opts.manifest, err = readManifest(root); err != nil {
+			return err
+		}
+	}
+	// Copy exclusions so repeated applies do not accumulate the manifest and labels paths.
+	exclusions := append(slices.Clone(opts.exclusions), manifestPath)
+	if opts.manifest.Labels != "" {
+		exclusions = append(exclusions, opts.manifest.Labels)
+	}
+
+	if err := compose(root, opts.manifest, opts); err != nil {
+		return err
+	}
+
+	attributes, err := git.ReadAttributes(root)
+	if err != nil {
+		return fmt.Errorf("failed to read .gitattributes: %w", err)
+	}
+
+	err = template.Apply(root, opts.params,
+		template.WithExclusions(exclusions),
+		template.WithAttributes(templateAttributes(attributes)),
+		template.WithPartials(opts.manifest.Partials),
+		template.WithResolver(providerResolver(opts)),
+		template.WithFuncs(functions.StringFuncs(opts.language)),
+		template.WithFuncs(functions.LicenseFuncs(opts.license, opts.params)),
 		template.WithLanguage(opts.language),
+		template.WithInput(opts.stdin()),
+		template.WithOutput(opts.Console.Stderr(), opts.isInteractive()),
 		template.WithLogger(opts.Log, opts.Verbose),
 		template.WithDelims(opts.leftDelim, opts.rightDelim),
 	)
+	if err != nil {
+		return err
+	}
+
+	if opts.manifest.Labels != "" {
+		if opts.manifest.labels, err = readLabels(root, opts.manifest.Labels); err != nil {
+			return fmt.Errorf("failed to read labels: %w", err)
+		}
+		if err = os.Remove(filepath.Join(root, opts.manifest.Labels)); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", opts.manifest.Labels, err)
+		}
+	}
+
+	if err = os.Remove(filepath.Join(root, manifestPath)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete %s: %w", manifestPath, err)
+	}
+
+	return nil
+}
+
+// templateAttributes returns how files are processed based on .gitattributes e.g.,
+// "*.sh template=false" to never process shell scripts, or "*.yml template-delims=<%,%>" to use alternate delimiters.
+func templateAttributes(attributes *git.Attributes) func(path string) processor.Attributes {
+	return func(path string) processor.Attributes {
+		attrs := processor.Attributes{
+			Binary: attributes.Binary(path),
+		}
+
+		matched := attributes.Match(path)
+		if attr, ok := matched["template"]; ok {
+			attrs.Skip = attr.IsUnset() || attr.IsValueSet() && attr.Value() == "false"
+		}
+		if attr, ok := matched["template-delims"]; ok && attr.IsValueSet() {
+			attrs.LeftDelim, attrs.RightDelim, _ = strings.Cut(attr.Value(), ",")
+		}
+
+		return attrs
+	}
 }
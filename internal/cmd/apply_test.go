@@ -0,0 +1,125 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/heaths/gh-template/internal/git"
+	"github.com/heaths/gh-template/internal/template/processor"
+	"github.com/heaths/go-console"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnsureClean(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	assert.NoError(t, ensureClean(root), "not a repository")
+
+	_, err := gogit.PlainInit(root, false)
+	require.NoError(t, err)
+	assert.NoError(t, ensureClean(root), "empty repository")
+
+	require.NoError(t, os.WriteFile(filepath.Join(root, "README.md"), []byte("# Example\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "main.go"), []byte("package main\n"), 0o644))
+	assert.EqualError(t, ensureClean(root), "working tree has uncommitted changes; commit or stash them, or pass --force to apply anyway:\n  ?? README.md\n  ?? main.go")
+}
+
+func TestTemplateAttributes(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".gitattributes"), []byte(`
+*.png binary
+*.sh template=false
+*.ps1 -template
+*.yml template-delims=<%,%>
+ci.yml template
+`), 0o644))
+
+	attributes, err := git.ReadAttributes(root)
+	require.NoError(t, err)
+
+	tests := []struct {
+		path string
+		want processor.Attributes
+	}{
+		{path: "README.md"},
+		{path: "logo.png", want: processor.Attributes{Binary: true}},
+		{path: "build.sh", want: processor.Attributes{Skip: true}},
+		{path: "build.ps1", want: processor.Attributes{Skip: true}},
+		{path: ".github/dependabot.yml", want: processor.Attributes{LeftDelim: "<%", RightDelim: "%>"}},
+		{path: "ci.yml", want: processor.Attributes{LeftDelim: "<%", RightDelim: "%>"}},
+	}
+
+	fn := templateAttributes(attributes)
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, fn(tt.path), tt.path)
+	}
+}
+
+func TestApplyDir_license(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "main.go"), []byte("// {{spdx}}\npackage main\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "README.md"), []byte(`License: {{param "license"}}`), 0o644))
+
+	opts := &applyOptions{
+		GlobalOptions: &GlobalOptions{
+			Console: console.Fake(),
+		},
+		license: "MIT",
+		params: map[string]string{
+			"license": "Proprietary",
+		},
+	}
+	require.NoError(t, applyDir(root, opts))
+
+	content, err := os.ReadFile(filepath.Join(root, "main.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "// SPDX-License-Identifier: MIT\npackage main\n", string(content))
+
+	// The template's own license parameter is not answered by --license.
+	content, err = os.ReadFile(filepath.Join(root, "README.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "License: Proprietary", string(content))
+
+	// Without --license, license and spdx render nothing.
+	opts.license = ""
+	require.NoError(t, os.WriteFile(filepath.Join(root, "main.go"), []byte("// {{spdx}}{{license}}\n"), 0o644))
+	require.NoError(t, applyDir(root, opts))
+
+	content, err = os.ReadFile(filepath.Join(root, "main.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "// \n", string(content))
+}
+
+func TestApplyDir_exclusions(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "README.md"), []byte(`# {{param "name"}}`), 0o644))
+
+	opts := &applyOptions{
+		GlobalOptions: &GlobalOptions{
+			Console: console.Fake(),
+		},
+		exclusions: []string{".github/workflows"},
+		params: map[string]string{
+			"name": "example",
+		},
+	}
+
+	for i := 0; i < 2; i++ {
+		opts.manifest = nil
+		require.NoError(t, applyDir(root, opts))
+		assert.Equal(t, []string{".github/workflows"}, opts.exclusions)
+	}
+}
@@ -0,0 +1,355 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package cmd
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/cli/go-gh/pkg/tableprinter"
+	"github.com/heaths/go-console"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+func BatchCmd(globalOpts *GlobalOptions) *cobra.Command {
+	opts := &batchOptions{}
+	var teams, collaborators []string
+	cmd := &cobra.Command{
+		Use:         "batch --template repository --from file",
+		Short:       "Creates and formats many repositories from a template",
+		Long:        "Creates a repository for each row of a CSV or YAML file then formats any templates found. Each row must specify a name, and may specify an owner, description, and visibility. All other columns are parameters. Because parameters are never prompted for, rows or --param must specify all parameters.",
+		Annotations: annotations(variables + cloneVariables + sourceVariables),
+		Args:        cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			opts.GlobalOptions = globalOpts
+
+			if opts.concurrency < 1 {
+				return fmt.Errorf("--concurrency must be at least 1")
+			}
+
+			parseSettingsFlags(cmd, &opts.settings)
+			if opts.access, err = parseAccessFlags(teams, collaborators); err != nil {
+				return
+			}
+
+			return batch(opts)
+		},
+	}
+
+	// Add `apply` flags and parsing, validation pre-run.
+	applyFlags(cmd, &opts.applyOptions)
+	cmd.Flags().Lookup("license").Usage = licenseUsage
+
+	cmd.Flags().StringVar(&opts.template, "template", "", "Make the new repositories based on a template `repository`")
+	cmd.Flags().StringVar(&opts.owner, "owner", "", "Create repositories for the user or organization `owner` unless a row specifies an owner (default current user)")
+	cmd.Flags().StringVar(&opts.from, "from", "", "CSV or YAML `file` with a row for each repository to create")
+	cmd.Flags().IntVarP(&opts.concurrency, "concurrency", "c", 4, "Maximum `number` of repositories to create at once")
+	cmd.Flags().BoolVar(&opts.json, "json", false, "Output results as JSON")
+	cmd.Flags().BoolVar(&opts.labels, "labels", false, "Clone labels from template repository or labels file declared by the template")
+	cmd.Flags().StringVar(&opts.secretsFile, "secrets-file", "", "Read Actions secret values from a `file` of NAME=value lines")
+	cmd.Flags().BoolVar(&opts.skipProtection, "skip-protection", false, "Do not apply branch protection rules and rulesets declared by the template")
+	settingsFlags(cmd, &opts.settings)
+	accessFlags(cmd, &teams, &collaborators)
+	cmd.Flags().BoolVar(&opts.push, "push", false, "Commit and push formatted templates before applying branch protection rules and rulesets declared by the template")
+	cmd.MarkFlagRequired("template") // nolint:errcheck
+	cmd.MarkFlagRequired("from")     // nolint:errcheck
+
+	// New repositories are private unless another visibility is specified.
+	cmd.Flags().BoolVar(&opts.internal, "internal", false, "Make the new repositories internal")
+	cmd.Flags().BoolVar(&opts.private, "private", false, "Make the new repositories private (default)")
+	cmd.Flags().BoolVar(&opts.public, "public", false, "Make the new repositories public")
+	cmd.MarkFlagsMutuallyExclusive("internal", "private", "public")
+
+	return cmd
+}
+
+type batchOptions struct {
+	applyOptions
+
+	owner       string
+	template    string
+	from        string
+	concurrency int
+	json        bool
+
+	labels         bool
+	settings       repositorySettings
+	access         accessSettings
+	secretsFile    string
+	skipProtection bool
+	push           bool
+
+	internal bool
+	private  bool
+	public   bool
+}
+
+// batchRow describes a repository to create. All other columns are parameters.
+type batchRow struct {
+	Name        string
+	Owner       string
+	Description string
+	Visibility  string
+	Params      map[string]string
+}
+
+type batchResult struct {
+	Name       string `json:"name"`
+	Repository string `json:"repository,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// batchClone clones each row and can be replaced for testing.
+var batchClone = clone
+
+func batch(opts *batchOptions) error {
+	rows, err := readBatch(opts.from)
+	if err != nil {
+		return err
+	}
+
+	// Rows share a context so that no more rows are started once interrupted, and each row rolls back its own changes.
+	ctx, cancel := context.WithCancel(opts.context())
+	defer cancel()
+
+	results := make([]batchResult, len(rows))
+	sem := make(chan struct{}, opts.concurrency)
+	var wg sync.WaitGroup
+
+	opts.Console.StartProgress(fmt.Sprintf("Creating %d repositories", len(rows)))
+	for i, row := range rows {
+		wg.Add(1)
+		go func(i int, row batchRow) {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			results[i].Name = row.Name
+			if ctx.Err() != nil {
+				results[i].Error = errInterrupted.Error()
+				return
+			}
+
+			cloneOpts := opts.cloneOptions(ctx, row)
+			if err := batchClone(cloneOpts); err != nil {
+				opts.logVerbose("failed to create %s: %v", row.Name, err)
+				results[i].Error = err.Error()
+				return
+			}
+
+			repo := cloneOpts.Repo
+			results[i].Repository = fmt.Sprintf("https://%s/%s/%s", repo.Host(), repo.Owner(), repo.Name())
+		}(i, row)
+	}
+	wg.Wait()
+	opts.Console.StopProgress()
+
+	failed := 0
+	for _, result := range results {
+		if result.Error != "" {
+			failed++
+		}
+	}
+
+	if opts.json {
+		enc := json.NewEncoder(opts.Console.Stdout())
+		enc.SetIndent("", "  ")
+		if err = enc.Encode(results); err != nil {
+			return err
+		}
+	} else if err = printBatch(opts, results); err != nil {
+		return err
+	}
+
+	if ctx.Err() != nil {
+		return errInterrupted
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to create %d of %d repositories", failed, len(results))
+	}
+
+	return nil
+}
+
+func printBatch(opts *batchOptions, results []batchResult) error {
+	width := 80
+	if opts.Console.IsStdoutTTY() {
+		var err error
+		if width, _, err = opts.Console.Size(); err != nil {
+			return err
+		}
+	}
+
+	table := tableprinter.New(opts.Console.Stdout(), opts.Console.IsStdoutTTY(), width)
+	cs := opts.Console.ColorScheme()
+	for _, result := range results {
+		if result.Error != "" {
+			table.AddField(result.Name, tableprinter.WithColor(cs.Red))
+			table.AddField(result.Error)
+		} else {
+			table.AddField(result.Name, tableprinter.WithColor(cs.Green))
+			table.AddField(result.Repository)
+		}
+		table.EndRow()
+	}
+
+	return table.Render()
+}
+
+// cloneOptions returns independent options to clone row, since repositories are created concurrently.
+func (opts *batchOptions) cloneOptions(ctx context.Context, row batchRow) *cloneOptions {
+	globalOpts := *opts.GlobalOptions
+	globalOpts.Console = batchConsole{opts.Console}
+	globalOpts.Context = ctx
+	globalOpts.Repo = nil
+
+	applyOpts := opts.applyOptions
+	applyOpts.GlobalOptions = &globalOpts
+	applyOpts.exclusions = append([]string(nil), opts.exclusions...)
+	applyOpts.manifest = nil
+	applyOpts.params = make(map[string]string, len(opts.params)+len(row.Params))
+	for name, value := range opts.params {
+		applyOpts.params[name] = value
+	}
+	for name, value := range row.Params {
+		applyOpts.params[name] = value
+	}
+
+	cloneOpts := &cloneOptions{
+		applyOptions:   applyOpts,
+		owner:          opts.owner,
+		name:           row.Name,
+		description:    row.Description,
+		template:       opts.template,
+		labels:         opts.labels,
+		labelOptions:   labelOptions{mode: "merge"},
+		settings:       opts.settings,
+		access:         opts.access,
+		secretsFile:    opts.secretsFile,
+		skipProtection: opts.skipProtection,
+		push:           opts.push,
+	}
+
+	cloneOpts.settings.Topics = append([]string(nil), opts.settings.Topics...)
+
+	if row.Owner != "" {
+		cloneOpts.owner = row.Owner
+	}
+
+	visibility := strings.ToLower(row.Visibility)
+	switch {
+	case visibility == "internal" || visibility == "" && opts.internal:
+		cloneOpts.internal = true
+	case visibility == "public" || visibility == "" && opts.public:
+		cloneOpts.public = true
+	default:
+		cloneOpts.private = true
+	}
+
+	return cloneOpts
+}
+
+// readBatch reads rows from a YAML file with a .yml or .yaml extension, or a CSV file with a header row.
+func readBatch(path string) ([]batchRow, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []map[string]string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yml", ".yaml":
+		if err = yaml.NewDecoder(f).Decode(&records); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+	default:
+		r := csv.NewReader(f)
+		r.TrimLeadingSpace = true
+
+		var header []string
+		if header, err = r.Read(); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		for {
+			values, err := r.Read()
+			if errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", path, err)
+			}
+
+			record := make(map[string]string, len(header))
+			for i, name := range header {
+				record[strings.TrimSpace(name)] = values[i]
+			}
+			records = append(records, record)
+		}
+	}
+
+	rows := make([]batchRow, 0, len(records))
+	names := make(map[string]bool, len(records))
+	for i, record := range records {
+		row := batchRow{Params: make(map[string]string)}
+		for name, value := range record {
+			switch name {
+			case "name":
+				row.Name = value
+			case "owner":
+				row.Owner = value
+			case "description":
+				row.Description = value
+			case "visibility":
+				row.Visibility = value
+			default:
+				row.Params[name] = value
+			}
+		}
+
+		if row.Name == "" {
+			return nil, fmt.Errorf("failed to read %s: row %d has no name", path, i+1)
+		}
+		if names[row.Name] {
+			return nil, fmt.Errorf("failed to read %s: duplicate name %s", path, row.Name)
+		}
+		names[row.Name] = true
+
+		switch strings.ToLower(row.Visibility) {
+		case "", "public", "private", "internal":
+		default:
+			return nil, fmt.Errorf("failed to read %s: invalid visibility %q for %s", path, row.Visibility, row.Name)
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// batchConsole discards output and never prompts so that repositories can be created concurrently.
+type batchConsole struct {
+	console.Console
+}
+
+func (batchConsole) Stdout() io.Writer                               { return io.Discard }
+func (batchConsole) Stderr() io.Writer                               { return io.Discard }
+func (batchConsole) Stdin() io.Reader                                { return strings.NewReader("") }
+func (batchConsole) IsStdoutTTY() bool                               { return false }
+func (batchConsole) IsStderrTTY() bool                               { return false }
+func (batchConsole) IsStdinTTY() bool                                { return false }
+func (batchConsole) Write(p []byte) (int, error)                     { return len(p), nil }
+func (batchConsole) StartProgress(string, ...console.ProgressOption) {}
+func (batchConsole) StopProgress()                                   {}
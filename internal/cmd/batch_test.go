@@ -0,0 +1,261 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cli/go-gh/pkg/repository"
+	"github.com/heaths/go-console"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadBatch(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    []batchRow
+		wantErr string
+	}{
+		{
+			name: "csv",
+			file: "services.csv",
+			content: `name, description, visibility, port
+orders, Order service, public, 8080
+payments,,,8081
+`,
+			want: []batchRow{
+				{Name: "orders", Description: "Order service", Visibility: "public", Params: map[string]string{"port": "8080"}},
+				{Name: "payments", Params: map[string]string{"port": "8081"}},
+			},
+		},
+		{
+			name: "yaml",
+			file: "students.yml",
+			content: `- name: alice-hw1
+  owner: classroom
+  student: Alice
+- name: bob-hw1
+  owner: classroom
+  student: Bob
+  visibility: Internal
+`,
+			want: []batchRow{
+				{Name: "alice-hw1", Owner: "classroom", Params: map[string]string{"student": "Alice"}},
+				{Name: "bob-hw1", Owner: "classroom", Visibility: "Internal", Params: map[string]string{"student": "Bob"}},
+			},
+		},
+		{
+			name:    "empty yaml",
+			file:    "empty.yaml",
+			content: "",
+			want:    []batchRow{},
+		},
+		{
+			name:    "missing name",
+			file:    "services.csv",
+			content: "name,port\n,8080\n",
+			wantErr: "row 1 has no name",
+		},
+		{
+			name:    "duplicate name",
+			file:    "services.csv",
+			content: "name\norders\norders\n",
+			wantErr: "duplicate name orders",
+		},
+		{
+			name:    "invalid visibility",
+			file:    "services.csv",
+			content: "name,visibility\norders,secret\n",
+			wantErr: `invalid visibility "secret" for orders`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), tt.file)
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o644))
+
+			got, err := readBatch(path)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestBatch(t *testing.T) {
+	from := filepath.Join(t.TempDir(), "services.csv")
+	require.NoError(t, os.WriteFile(from, []byte("name,port\norders,8080\npayments,8081\nshipping,8082\n"), 0o644))
+
+	var running, maxRunning int32
+	clone := batchClone
+	batchClone = func(opts *cloneOptions) error {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			max := atomic.LoadInt32(&maxRunning)
+			if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		assert.False(t, opts.Console.IsStdinTTY())
+		assert.Equal(t, "heaths/template-golang", opts.template)
+		assert.Equal(t, "default", opts.params["owner"])
+		assert.True(t, opts.private)
+		assert.Equal(t, "main", opts.settings.DefaultBranch)
+		assert.Equal(t, []string{"go"}, opts.settings.Topics)
+		assert.Equal(t, map[string]string{"developers": "push"}, opts.access.Teams)
+		assert.Same(t, opts.Context, opts.context())
+		assert.NoError(t, opts.context().Err())
+
+		if opts.name == "payments" {
+			return errors.New("cannot prompt for parameter \"currency\"")
+		}
+		assert.Equal(t, map[string]string{"owner": "default", "port": opts.params["port"]}, opts.params)
+
+		var err error
+		opts.Repo, err = repository.ParseWithHost("heaths/"+opts.name, "github.com")
+		return err
+	}
+	t.Cleanup(func() { batchClone = clone })
+
+	stdout := &bytes.Buffer{}
+	opts := &batchOptions{
+		applyOptions: applyOptions{
+			GlobalOptions: &GlobalOptions{
+				Console: console.Fake(
+					console.WithStdout(stdout),
+					console.WithStdinTTY(true),
+					console.WithStderrTTY(true),
+				),
+			},
+			params: map[string]string{"owner": "default"},
+		},
+		template:    "heaths/template-golang",
+		from:        from,
+		concurrency: 2,
+		json:        true,
+		settings: repositorySettings{
+			DefaultBranch: "main",
+			Topics:        []string{"go"},
+		},
+		access: accessSettings{
+			Teams: map[string]string{"developers": "push"},
+		},
+	}
+
+	err := batch(opts)
+	assert.EqualError(t, err, "failed to create 1 of 3 repositories")
+	assert.LessOrEqual(t, maxRunning, int32(2))
+	assert.JSONEq(t, `[
+		{"name": "orders", "repository": "https://github.com/heaths/orders"},
+		{"name": "payments", "error": "cannot prompt for parameter \"currency\""},
+		{"name": "shipping", "repository": "https://github.com/heaths/shipping"}
+	]`, stdout.String())
+}
+
+func TestBatch_interrupted(t *testing.T) {
+	from := filepath.Join(t.TempDir(), "services.csv")
+	require.NoError(t, os.WriteFile(from, []byte("name\norders\npayments\nshipping\n"), 0o644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	var cloned int32
+	clone := batchClone
+	batchClone = func(opts *cloneOptions) error {
+		atomic.AddInt32(&cloned, 1)
+
+		// Interrupt while the first row is being created; the row rolls back when its context is done.
+		cancel()
+		<-opts.context().Done()
+		return errInterrupted
+	}
+	t.Cleanup(func() { batchClone = clone })
+
+	stdout := &bytes.Buffer{}
+	opts := &batchOptions{
+		applyOptions: applyOptions{
+			GlobalOptions: &GlobalOptions{
+				Console: console.Fake(console.WithStdout(stdout)),
+				Context: ctx,
+			},
+		},
+		template:    "heaths/template-golang",
+		from:        from,
+		concurrency: 1,
+		json:        true,
+	}
+
+	err := batch(opts)
+	assert.ErrorIs(t, err, errInterrupted)
+	assert.Equal(t, int32(1), cloned)
+	assert.JSONEq(t, `[
+		{"name": "orders", "error": "interrupted"},
+		{"name": "payments", "error": "interrupted"},
+		{"name": "shipping", "error": "interrupted"}
+	]`, stdout.String())
+}
+
+func TestBatchCloneOptions_missingParam(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "README.md"), []byte("# {{param \"name\"}} in {{param \"currency\" \"USD\"}}\n"), 0o644))
+
+	stdin := bytes.NewBufferString("EUR\n")
+	stderr := &bytes.Buffer{}
+	logs := &bytes.Buffer{}
+	opts := &batchOptions{
+		applyOptions: applyOptions{
+			GlobalOptions: &GlobalOptions{
+				Log: log.New(logs, "", 0),
+				Console: console.Fake(
+					console.WithStdin(stdin),
+					console.WithStdinTTY(true),
+					console.WithStderr(stderr),
+					console.WithStderrTTY(true),
+				),
+			},
+		},
+	}
+
+	cloneOpts := opts.cloneOptions(context.Background(), batchRow{
+		Name:   "payments",
+		Params: map[string]string{"name": "payments"},
+	})
+	cloneOpts.root = root
+
+	err := apply(&cloneOpts.applyOptions)
+	assert.EqualError(t, err, "failed to process 1 template")
+	assert.Contains(t, logs.String(), `cannot prompt for parameter "currency"`)
+	assert.Equal(t, "EUR\n", stdin.String())
+	assert.Empty(t, stderr.String())
+
+	content, err := os.ReadFile(filepath.Join(root, "README.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "# {{param \"name\"}} in {{param \"currency\" \"USD\"}}\n", string(content))
+}
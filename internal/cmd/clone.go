@@ -4,20 +4,29 @@
 package cmd
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strings"
 
-	"github.com/cli/go-gh"
+	"github.com/cli/go-gh/pkg/repository"
+	"github.com/heaths/gh-template/internal/functions"
+	"github.com/heaths/gh-template/internal/git"
 	"github.com/spf13/cobra"
 )
 
 func CloneCmd(globalOpts *GlobalOptions) *cobra.Command {
 	opts := &cloneOptions{}
+	var teams, collaborators []string
 	cmd := &cobra.Command{
-		Use:         "clone name --template repository",
+		Use:         "clone [owner/]name --template repository",
 		Short:       "Clones and formats a template repository",
-		Long:        "Clones a template repository then formats any templates found. Any parameters not passed to --param will prompt the user for a value. These may include a default value used if the user does not enter a value.",
-		Annotations: annotations(),
+		Long:        "Clones a template repository then formats any templates found. Any parameters not passed to --param will prompt the user for a value. These may include a default value used if the user does not enter a value.\n\nIf no visibility is specified, you are prompted for one. Teams passed to --team are granted access after the repository is created, read by default, or with another permission as slug:permission.",
+		Annotations: annotations(variables + cloneVariables + sourceVariables),
 		Args:        cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) (err error) {
 			opts.GlobalOptions = globalOpts
@@ -26,6 +35,25 @@ func CloneCmd(globalOpts *GlobalOptions) *cobra.Command {
 				return fmt.Errorf("expected template repository name")
 			}
 			opts.name = args[0]
+			if owner, name, ok := strings.Cut(opts.name, "/"); ok {
+				if owner == "" || name == "" || strings.Contains(name, "/") {
+					return fmt.Errorf("expected repository name or owner/name: %s", args[0])
+				}
+				if opts.owner != "" && !strings.EqualFold(opts.owner, owner) {
+					return fmt.Errorf("--owner %s conflicts with owner %s of %s", opts.owner, owner, args[0])
+				}
+				opts.owner, opts.name = owner, name
+			}
+
+			parseSettingsFlags(cmd, &opts.settings)
+
+			if err = opts.labelOptions.validate(); err != nil {
+				return
+			}
+
+			if opts.access, err = parseAccessFlags(teams, collaborators); err != nil {
+				return
+			}
 
 			return clone(opts)
 		},
@@ -33,8 +61,10 @@ func CloneCmd(globalOpts *GlobalOptions) *cobra.Command {
 
 	// Add `apply` flags and parsing, validation pre-run.
 	applyFlags(cmd, &opts.applyOptions)
+	cmd.Flags().Lookup("license").Usage = licenseUsage
 
 	cmd.Flags().StringVarP(&opts.description, "description", "d", "", "Description of the repository")
+	cmd.Flags().StringVar(&opts.owner, "owner", "", "Create the new repository for the user or organization `owner` (default current user)")
 	cmd.Flags().StringVar(&opts.template, "template", "", "Make the new `repository` based on a template repository")
 	cmd.Flags().StringVarP(&opts.remote, "remote", "r", "", "Specify remote name for the new repository")
 	cmd.Flags().StringVar(&opts.homepage, "homepage", "", "Repository home page `URL`")
@@ -43,21 +73,33 @@ func CloneCmd(globalOpts *GlobalOptions) *cobra.Command {
 	cmd.Flags().BoolVar(&opts.disableIssues, "disable-issues", false, "Disable issues in the new repository")
 	cmd.Flags().BoolVar(&opts.disableWiki, "disable-wiki", false, "Disable wiki in the new repository")
 	cmd.Flags().BoolVar(&opts.includeAllBranches, "include-all-branches", false, "Include all branches from template repository")
-	cmd.Flags().BoolVar(&opts.labels, "labels", false, "Clone labels from template repository")
+	cmd.Flags().BoolVar(&opts.labels, "labels", false, "Clone labels from template repository or labels file declared by the template")
+	cmd.Flags().StringVar(&opts.labelOptions.mode, "labels-mode", "merge", "How to clone labels: merge to create and update labels, or replace to also delete other labels")
+	cmd.Flags().BoolVar(&opts.labelOptions.deleteDefaults, "delete-default-labels", false, "Delete labels GitHub creates by default unless cloned")
+	cmd.Flags().StringSliceVar(&opts.labelOptions.include, "label-include", nil, "Only clone labels matching `patterns`")
+	cmd.Flags().StringSliceVar(&opts.labelOptions.exclude, "label-exclude", nil, "Do not clone labels matching `patterns`")
+
+	settingsFlags(cmd, &opts.settings)
+	cmd.Flags().StringVar(&opts.secretsFile, "secrets-file", "", "Read Actions secret values from a `file` of NAME=value lines instead of prompting")
+	cmd.Flags().BoolVar(&opts.skipProtection, "skip-protection", false, "Do not apply branch protection rules and rulesets declared by the template")
+	cmd.Flags().BoolVar(&opts.push, "push", false, "Commit and push formatted templates before applying branch protection rules and rulesets declared by the template")
 
-	// Do not determine which visibility to use; let `gh repo create` handle that downstream.
 	cmd.Flags().BoolVar(&opts.internal, "internal", false, "Make the new repository internal")
 	cmd.Flags().BoolVar(&opts.private, "private", false, "Make the new repository private")
 	cmd.Flags().BoolVar(&opts.public, "public", false, "Make the new repository public")
-	cmd.Flags().StringVarP(&opts.team, "team", "t", "", "The `name` of the organization team to be granted access")
+	accessFlags(cmd, &teams, &collaborators)
 	cmd.MarkFlagsMutuallyExclusive("internal", "private", "public")
 
 	return cmd
 }
 
+// licenseUsage describes --license for commands that also render LICENSE.
+const licenseUsage = "SPDX license `identifier` to render as LICENSE if not already present, and used by the license and spdx functions e.g., MIT"
+
 type cloneOptions struct {
 	applyOptions
 
+	owner       string
 	name        string
 	description string
 	template    string
@@ -68,73 +110,340 @@ type cloneOptions struct {
 	disableWiki        bool
 	includeAllBranches bool
 	labels             bool
+	labelOptions       labelOptions
 
 	internal bool
 	private  bool
 	public   bool
-	team     string
+
+	settings       repositorySettings
+	access         accessSettings
+	secretsFile    string
+	skipProtection bool
+	push           bool
+}
+
+func (opts *cloneOptions) visibility() string {
+	switch {
+	case opts.internal:
+		return "internal"
+	case opts.private:
+		return "private"
+	case opts.public:
+		return "public"
+	default:
+		return ""
+	}
 }
 
 func clone(opts *cloneOptions) (err error) {
-	args := make([]string, 0, 18)
-	args = append(args, "repo", "create", opts.name, "--template", opts.template, "--clone")
-	if opts.description != "" {
-		args = append(args, "--description", opts.description)
+	// The repository is cloned into a directory of the same name.
+	opts.root = opts.name
+	opts.origin = opts.template
+	opts.addLicense = true
+
+	// Resume a previous clone that failed after the repository was created.
+	var state *cloneState
+	if _, err = os.Stat(filepath.Join(opts.root, ".git")); err == nil {
+		if state, err = readCloneState(opts.root); err != nil {
+			return fmt.Errorf("failed to read previous clone of %s: %w", opts.name, err)
+		}
+		if state == nil {
+			return fmt.Errorf("directory %s already exists and was not created by a previous clone", opts.root)
+		}
+		if state.Template != opts.template {
+			return fmt.Errorf("directory %s was created from template %s, not %s", opts.root, state.Template, opts.template)
+		}
+		if opts.Repo, err = repository.Parse(state.Repository); err != nil {
+			return fmt.Errorf("failed to read previous clone of %s: %w", opts.name, err)
+		}
+		if opts.owner != "" && !strings.EqualFold(opts.owner, opts.Repo.Owner()) {
+			return fmt.Errorf("directory %s was created for owner %s, not %s", opts.root, opts.Repo.Owner(), opts.owner)
+		}
+		fmt.Fprintf(opts.Console.Stderr(), "Resuming previous clone of %s\n", opts.name)
+	} else if _, err = os.Stat(opts.root); err == nil {
+		return fmt.Errorf("directory %s already exists", opts.root)
+	} else {
+		if opts.Repo, err = createRepository(opts); err != nil {
+			return
+		}
+
+		if state, err = newCloneState(opts.root, opts.template); err != nil {
+			return
+		}
+		state.Repository = fmt.Sprintf("%s/%s/%s", opts.Repo.Host(), opts.Repo.Owner(), opts.Repo.Name())
+		if err = state.complete(stepCreate); err != nil {
+			return fmt.Errorf("failed to save clone progress: %w", err)
+		}
 	}
-	if opts.remote != "" {
-		args = append(args, "--remote", opts.remote)
+
+	// Parameters passed on the command line take precedence over those from a previous clone.
+	for name, value := range state.Params {
+		if _, ok := opts.params[name]; !ok {
+			opts.params[name] = value
+		}
 	}
-	if opts.homepage != "" {
-		args = append(args, "--homepage", opts.homepage)
+	state.Params = opts.params
+	if state.Manifest != nil {
+		opts.manifest = state.Manifest
 	}
-	if opts.disableIssues {
-		args = append(args, "--disable-issues")
+
+	opts.params["github.description"] = opts.description
+	opts.params["github.homepage"] = opts.homepage
+	// The visibility of a resumed clone is saved with its parameters if not specified again.
+	if visibility := opts.visibility(); visibility != "" {
+		opts.params["github.visibility"] = visibility
 	}
-	if opts.disableWiki {
-		args = append(args, "--disable-wiki")
+
+	if err := templateVariables(opts.GlobalOptions, opts.template, opts.params); err != nil {
+		opts.logVerbose("failed to get template information: %v", err)
 	}
-	if opts.includeAllBranches {
-		args = append(args, "--include-all-branches")
+
+	if err = resolveDefaultBranch(opts); err != nil {
+		return
 	}
-	if opts.internal {
-		args = append(args, "--internal")
-	} else if opts.private {
-		args = append(args, "--private")
-	} else if opts.public {
-		args = append(args, "--public")
+
+	// Run each step not already completed, saving progress after each.
+	run := func(step, progress string, fn func() error) error {
+		if state.done(step) {
+			opts.logVerbose("skipping %s step completed by a previous clone", step)
+			return nil
+		}
+		if opts.context().Err() != nil {
+			return errInterrupted
+		}
+
+		if progress != "" {
+			opts.Console.StartProgress(progress)
+		}
+		err := fn()
+		if progress != "" {
+			opts.Console.StopProgress()
+		}
+		if err != nil {
+			return err
+		}
+
+		if err = state.complete(step); err != nil {
+			return fmt.Errorf("failed to save clone progress: %w", err)
+		}
+		return nil
 	}
-	if opts.team != "" {
-		args = append(args, "--team", opts.team)
+
+	var summary *labelSummary
+	for _, s := range []struct {
+		step     string
+		progress string
+		fn       func() error
+	}{
+		{stepApply, "", func() error {
+			if err := apply(&opts.applyOptions); err != nil {
+				return err
+			}
+			state.Manifest = opts.manifest
+			return nil
+		}},
+		{stepSettings, "Configuring repository", func() error {
+			return configureRepository(opts.root, opts.settings, &opts.applyOptions)
+		}},
+		{stepAccess, "Granting access", func() error {
+			return grantAccess(opts.access, &opts.applyOptions)
+		}},
+		{stepLabels, "Syncing labels", func() error {
+			if !opts.labels && !opts.labelOptions.deleteDefaults {
+				return nil
+			}
+			s, err := cloneLabels(opts)
+			summary = &s
+			return err
+		}},
+		{stepSeed, "Seeding issues", func() error {
+			return seedRepository(&opts.applyOptions)
+		}},
+		{stepActions, "Configuring actions", func() error {
+			return configureActions(opts.secretsFile, &opts.applyOptions)
+		}},
+		{stepCommit, "", func() error {
+			if !opts.push {
+				return nil
+			}
+			return commitRepository(opts.root, opts)
+		}},
+		{stepPush, "Pushing repository", func() error {
+			if !opts.push {
+				return nil
+			}
+			return pushRepository(opts.root, opts)
+		}},
+		// Protect branches after any formatted templates are pushed, since rules may prevent pushing.
+		{stepProtection, "Protecting branches", func() error {
+			if opts.skipProtection {
+				opts.logVerbose("skipping branch protection and rulesets")
+				return nil
+			}
+			return protectRepository(&opts.applyOptions)
+		}},
+	} {
+		err = run(s.step, s.progress, s.fn)
+		if summary != nil {
+			fmt.Fprintln(opts.Console.Stdout(), *summary)
+			summary = nil
+		}
+		if err != nil {
+			return
+		}
 	}
 
-	opts.Console.StartProgress("Creating repository " + opts.name)
-	_, stderr, err := gh.Exec(args...)
-	opts.Console.StopProgress()
-	if err != nil {
-		fmt.Fprintln(opts.Console.Stderr(), stderr.String())
-		return fmt.Errorf("failed to create repository %s: %w", opts.name, err)
+	return state.remove()
+}
+
+// resolveDefaultBranch sets the github.defaultBranch parameter before templates are applied to the branch
+// passed to --default-branch or declared by the template, which is renamed after applying; otherwise, the current branch.
+func resolveDefaultBranch(opts *cloneOptions) (err error) {
+	if opts.manifest == nil {
+		if opts.manifest, err = readManifest(opts.rootDir()); err != nil {
+			return
+		}
 	}
 
-	err = os.Chdir(opts.name)
+	settings := opts.settings
+	if opts.manifest.Repository != nil {
+		settings = opts.manifest.Repository.merge(opts.settings)
+	}
+
+	if settings.DefaultBranch != "" {
+		branch, err := expand(settings.DefaultBranch, &opts.applyOptions)
+		if err != nil {
+			return fmt.Errorf("failed to expand default branch: %w", err)
+		}
+		opts.params["github.defaultBranch"] = branch
+	} else if branch, err := git.Branch(opts.rootDir()); err == nil {
+		opts.params["github.defaultBranch"] = branch
+	} else {
+		opts.logVerbose("failed to get default branch: %v", err)
+	}
+
+	return nil
+}
+
+// promptVisibility prompts for the visibility of the new repository.
+func promptVisibility(opts *cloneOptions) error {
+	reader := bufio.NewReader(opts.stdin())
+	for {
+		fmt.Fprint(opts.Console.Stderr(), "\033[32mVisibility? \033[90m[public/private/internal]\033[0m: ")
+
+		answer, err := reader.ReadString('\n')
+		if err != nil && !(errors.Is(err, io.EOF) && answer != "") {
+			return err
+		}
+
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "public":
+			opts.public = true
+			return nil
+		case "private":
+			opts.private = true
+			return nil
+		case "internal":
+			opts.internal = true
+			return nil
+		}
+
+		fmt.Fprintln(opts.Console.Stderr(), "\033[31mExpected public, private, or internal. Please try again.\033[0m")
+	}
+}
+
+// createRepository creates a new repository from the template and clones it into the root directory.
+func createRepository(opts *cloneOptions) (repository.Repository, error) {
+	template, err := repository.Parse(opts.template)
 	if err != nil {
-		return fmt.Errorf("failed to change directory to %s: %w", opts.name, err)
+		return nil, err
 	}
 
-	if opts.labels {
-		opts.Console.StartProgress("Cloning labels")
-		_, stderr, err = gh.Exec("label", "clone", opts.template, "--force")
+	// Like `gh repo create`, prompt for visibility if not specified unless continuing from an existing repository.
+	var repo generatedRepository
+	if opts.visibility() == "" {
+		repo, err = resolveVisibility(opts)
+	}
+	if err == nil {
+		opts.Console.StartProgress("Creating repository " + opts.name)
+		repo, err = generateRepository(opts)
 		opts.Console.StopProgress()
-		if err != nil {
-			fmt.Fprintln(opts.Console.Stderr(), stderr.String())
-			return fmt.Errorf("failed to clone labels from %s: %w", opts.template, err)
+	}
+	if errors.Is(err, errNameTaken) {
+		repo, err = existingRepository(repo, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	opts.Console.StartProgress("Cloning repository " + opts.name)
+	err = cloneRepository(repo, opts.root, opts)
+	opts.Console.StopProgress()
+	if err != nil {
+		return nil, err
+	}
+
+	return repository.ParseWithHost(repo.Owner+"/"+repo.Name, template.Host())
+}
+
+// writeLicense adds a LICENSE file to root if the license is set and root does not already contain a license.
+func writeLicense(root string, opts *applyOptions) error {
+	if opts.license == "" {
+		return nil
+	}
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := strings.ToUpper(entry.Name())
+		if strings.HasPrefix(name, "LICENSE") || strings.HasPrefix(name, "COPYING") {
+			opts.logVerbose("skipping license since %q already exists", entry.Name())
+			return nil
 		}
 	}
 
-	// Now that we're in a repo...
-	opts.Repo, err = gh.CurrentRepository()
+	text, err := functions.LicenseFunc(opts.license, opts.params)()
+	if err != nil {
+		return fmt.Errorf("failed to render license %s: %w", opts.license, err)
+	}
+
+	return os.WriteFile(filepath.Join(root, "LICENSE"), []byte(text), 0o644)
+}
+
+func templateVariables(opts *GlobalOptions, template string, params map[string]string) error {
+	repo, err := repository.Parse(template)
+	if err != nil {
+		return err
+	}
+
+	params["template.owner"] = repo.Owner()
+	params["template.repo"] = repo.Name()
+
+	client, err := opts.restClient(repo.Host())
+	if err != nil {
+		return err
+	}
+
+	var info struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+	err = client.Get(fmt.Sprintf("repos/%s/%s", repo.Owner(), repo.Name()), &info)
+	if err != nil {
+		return err
+	}
+	params["template.ref"] = info.DefaultBranch
+
+	var commit struct {
+		SHA string
+	}
+	err = client.Get(fmt.Sprintf("repos/%s/%s/commits/%s", repo.Owner(), repo.Name(), url.PathEscape(info.DefaultBranch)), &commit)
 	if err != nil {
-		return fmt.Errorf("failed to get repository information: %w", err)
+		return err
 	}
+	params["template.sha"] = commit.SHA
 
-	return apply(&opts.applyOptions)
+	return nil
 }
@@ -0,0 +1,207 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/heaths/go-console"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestTemplateVariables(t *testing.T) {
+	t.Cleanup(gock.Off)
+
+	gock.New("https://api.github.com").
+		Get("/repos/heaths/template-golang").
+		Reply(200).
+		JSON(`{
+			"default_branch": "main"
+		}`)
+	gock.New("https://api.github.com").
+		Get("/repos/heaths/template-golang/commits/main").
+		Reply(200).
+		JSON(`{
+			"sha": "0123456789abcdef0123456789abcdef01234567"
+		}`)
+
+	opts := &GlobalOptions{
+		authToken: "***",
+		host:      "github.com",
+	}
+	params := make(map[string]string)
+
+	err := templateVariables(opts, "heaths/template-golang", params)
+	assert.NoError(t, err)
+	assert.True(t, gock.IsDone(), pendingMocks(gock.Pending()))
+
+	assert.Equal(t, map[string]string{
+		"template.owner": "heaths",
+		"template.repo":  "template-golang",
+		"template.ref":   "main",
+		"template.sha":   "0123456789abcdef0123456789abcdef01234567",
+	}, params)
+}
+
+func TestPromptVisibility(t *testing.T) {
+	t.Parallel()
+
+	fake := console.Fake(
+		console.WithStdin(bytes.NewBufferString("secret\nInternal\n")),
+		console.WithStdinTTY(true),
+		console.WithStderrTTY(true),
+	)
+	opts := &cloneOptions{}
+	opts.GlobalOptions = &GlobalOptions{
+		Console: fake,
+	}
+
+	require.NoError(t, promptVisibility(opts))
+	assert.True(t, opts.internal)
+	assert.False(t, opts.private)
+	assert.False(t, opts.public)
+
+	_, stderr, _ := fake.Buffers()
+	assert.Contains(t, stderr.String(), "Expected public, private, or internal.")
+}
+
+func TestResolveVisibility(t *testing.T) {
+	tests := []struct {
+		name        string
+		owner       string
+		mocks       func()
+		interactive bool
+		want        generatedRepository
+		wantErr     string
+		wantPublic  bool
+	}{
+		{
+			name: "prompt",
+			mocks: func() {
+				gock.New("https://api.github.com").
+					Get("/user").
+					Reply(200).
+					JSON(`{"login": "heaths"}`)
+				gock.New("https://api.github.com").
+					Get("/repos/heaths/example").
+					Reply(404).
+					JSON(`{"message": "Not Found"}`)
+			},
+			interactive: true,
+			wantPublic:  true,
+		},
+		{
+			name:  "required",
+			owner: "contoso",
+			mocks: func() {
+				gock.New("https://api.github.com").
+					Get("/repos/contoso/example").
+					Reply(404).
+					JSON(`{"message": "Not Found"}`)
+			},
+			wantErr: "--public, --private, or --internal required when not running interactively",
+		},
+		{
+			name:  "existing",
+			owner: "contoso",
+			mocks: func() {
+				gock.New("https://api.github.com").
+					Get("/repos/contoso/example").
+					Reply(200).
+					JSON(`{"name": "example", "owner": {"login": "contoso"}}`)
+			},
+			want:    generatedRepository{Owner: "contoso", Name: "example"},
+			wantErr: "name already exists: contoso/example",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(gock.Off)
+			tt.mocks()
+
+			opts := &cloneOptions{
+				owner:    tt.owner,
+				name:     "example",
+				template: "heaths/template-golang",
+			}
+			opts.GlobalOptions = &GlobalOptions{
+				Console: console.Fake(
+					console.WithStdin(bytes.NewBufferString("public\n")),
+					console.WithStdinTTY(tt.interactive),
+					console.WithStderrTTY(tt.interactive),
+				),
+				authToken: "***",
+				host:      "github.com",
+			}
+
+			got, err := resolveVisibility(opts)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantPublic, opts.public)
+			assert.True(t, gock.IsDone(), pendingMocks(gock.Pending()))
+		})
+	}
+}
+
+func TestResolveDefaultBranch(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		manifest string
+		flag     string
+		want     string
+	}{
+		{
+			name:     "manifest",
+			manifest: "repository:\n  defaultBranch: '{{param \"branch\"}}'\n",
+			want:     "main",
+		},
+		{
+			name:     "flag",
+			manifest: "repository:\n  defaultBranch: main\n",
+			flag:     "trunk",
+			want:     "trunk",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			root := t.TempDir()
+			_, err := gogit.PlainInit(root, false)
+			require.NoError(t, err)
+			if tt.manifest != "" {
+				require.NoError(t, os.MkdirAll(filepath.Join(root, ".github"), 0o755))
+				require.NoError(t, os.WriteFile(filepath.Join(root, manifestPath), []byte(tt.manifest), 0o644))
+			}
+
+			opts := &cloneOptions{
+				settings: repositorySettings{DefaultBranch: tt.flag},
+			}
+			opts.GlobalOptions = &GlobalOptions{
+				Console: console.Fake(),
+			}
+			opts.root = root
+			opts.params = map[string]string{"branch": "main"}
+
+			require.NoError(t, resolveDefaultBranch(opts))
+			assert.Equal(t, tt.want, opts.params["github.defaultBranch"])
+			assert.NotNil(t, opts.manifest)
+		})
+	}
+}
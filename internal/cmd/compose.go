@@ -0,0 +1,225 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/cli/go-gh/pkg/auth"
+	"github.com/cli/go-gh/pkg/repository"
+	"github.com/heaths/gh-template/internal/git"
+	"github.com/heaths/gh-template/internal/template/processor"
+)
+
+const (
+	conflictSkip     = "skip"
+	conflictOverride = "override"
+	conflictAppend   = "append"
+)
+
+// dependency is another template repository overlaid onto the template.
+type dependency struct {
+	// Template is the repository in [HOST/]OWNER/REPO format.
+	Template string `yaml:"template"`
+	// Ref is the branch or tag, or the default branch if empty.
+	Ref string `yaml:"ref"`
+	// Conflict is how to handle files that already exist: "skip" (default), "override", or "append".
+	Conflict string `yaml:"conflict"`
+}
+
+func (d dependency) validate() error {
+	if d.Template == "" {
+		return fmt.Errorf("dependency template required")
+	}
+
+	switch d.Conflict {
+	case "", conflictSkip, conflictOverride, conflictAppend:
+		return nil
+	default:
+		return fmt.Errorf("dependency %s: unsupported conflict %q; expected %s, %s, or %s", d.Template, d.Conflict, conflictSkip, conflictOverride, conflictAppend)
+	}
+}
+
+// compose overlays all dependencies declared in m onto root in declared order.
+// Each dependency's own dependencies are overlaid immediately after it. Each template repository,
+// including the template being applied, is overlaid at most once regardless of ref.
+func compose(root string, m *manifest, opts *applyOptions) error {
+	visited := make(map[string]bool)
+	for _, template := range []string{opts.origin, opts.source} {
+		if template == "" {
+			continue
+		}
+		if repo, err := repository.Parse(template); err == nil {
+			visited[templateKey(repo)] = true
+		}
+	}
+
+	return composeDependencies(root, partialsDir(m), m.Dependencies, visited, opts)
+}
+
+func composeDependencies(root, partials string, dependencies []dependency, visited map[string]bool, opts *applyOptions) error {
+	for _, d := range dependencies {
+		if err := d.validate(); err != nil {
+			return err
+		}
+
+		repo, err := repository.Parse(d.Template)
+		if err != nil {
+			return fmt.Errorf("dependency %s: %w", d.Template, err)
+		}
+
+		key := templateKey(repo)
+		if visited[key] {
+			opts.logVerbose("skipping dependency %s already overlaid", d.Template)
+			continue
+		}
+		visited[key] = true
+
+		if err := composeDependency(root, partials, repo, d, visited, opts); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func composeDependency(root, partials string, repo repository.Repository, d dependency, visited map[string]bool, opts *applyOptions) error {
+	dir, err := os.MkdirTemp("", "gh-template-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, repo.Name())
+
+	opts.logVerbose("fetching dependency %s", d.Template)
+	if err = fetchTemplate(repo, d.Ref, src); err != nil {
+		return fmt.Errorf("failed to fetch dependency %s: %w", d.Template, err)
+	}
+
+	m, err := readManifest(src)
+	if err != nil {
+		return err
+	}
+
+	conflict := d.Conflict
+	if conflict == "" {
+		conflict = conflictSkip
+	}
+
+	// Partials declared by the dependency are overlaid into the template's partials directory.
+	dirs := partialsDirs{src: partialsDir(m), dst: partials}
+	if err = overlay(src, root, dirs, conflict, opts); err != nil {
+		return fmt.Errorf("failed to overlay dependency %s: %w", d.Template, err)
+	}
+
+	return composeDependencies(root, partials, m.Dependencies, visited, opts)
+}
+
+// fetchTemplate shallowly clones a template repository at ref, or the default branch if empty, into dir.
+// It can be replaced for testing.
+var fetchTemplate = func(repo repository.Repository, ref, dir string) error {
+	token, _ := auth.TokenForHost(repo.Host())
+	url := fmt.Sprintf("https://%s/%s/%s.git", repo.Host(), repo.Owner(), repo.Name())
+
+	return git.Clone(url, dir, git.CloneOptions{
+		Ref:   ref,
+		Depth: 1,
+		Token: token,
+	})
+}
+
+// templateKey identifies a template repository regardless of how it was specified.
+func templateKey(repo repository.Repository) string {
+	return strings.ToLower(repo.Host() + "/" + repo.Owner() + "/" + repo.Name())
+}
+
+// partialsDir returns the normalized partials directory declared by m, or the default partials directory.
+func partialsDir(m *manifest) string {
+	dir := strings.Trim(path.Clean("/"+filepath.ToSlash(m.Partials)), "/")
+	if dir == "" {
+		return processor.DefaultPartials
+	}
+	return dir
+}
+
+// partialsDirs maps the partials directory of an overlaid template to the partials directory of the template.
+type partialsDirs struct {
+	src string
+	dst string
+}
+
+// rename returns rel within the dst partials directory if rel is within the src partials directory.
+func (p partialsDirs) rename(rel string) string {
+	if p.src == "" || p.dst == "" || p.src == p.dst {
+		return rel
+	}
+	if rel == p.src || strings.HasPrefix(rel, p.src+"/") {
+		return p.dst + rel[len(p.src):]
+	}
+	return rel
+}
+
+// overlay copies files from src into dst, resolving files that already exist in dst using conflict.
+// Files in the src partials directory are copied into the dst partials directory.
+func overlay(src, dst string, partials partialsDirs, conflict string, opts *applyOptions) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+
+		if rel == ".git" || filepath.ToSlash(rel) == manifestPath {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		target := filepath.Join(dst, filepath.FromSlash(partials.rename(filepath.ToSlash(rel))))
+		if d.IsDir() {
+			return os.MkdirAll(target, 0o755)
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		existing, err := os.ReadFile(target)
+		switch {
+		case os.IsNotExist(err):
+		case err != nil:
+			return err
+		case conflict == conflictOverride:
+			opts.logVerbose("overriding %q", rel)
+		case conflict == conflictAppend:
+			opts.logVerbose("appending %q", rel)
+			if len(existing) > 0 && !bytes.HasSuffix(existing, []byte("\n")) {
+				existing = append(existing, '\n')
+			}
+			content = append(existing, content...)
+		default:
+			opts.logVerbose("skipping existing %q", rel)
+			return nil
+		}
+
+		return os.WriteFile(target, content, info.Mode().Perm())
+	})
+}
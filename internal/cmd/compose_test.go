@@ -0,0 +1,202 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cli/go-gh/pkg/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOverlay(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		conflict string
+		want     map[string]string
+	}{
+		{
+			name:     "skip",
+			conflict: conflictSkip,
+			want: map[string]string{
+				".gitignore":           "bin/",
+				"LICENSE.txt":          "base license",
+				"README.md":            "template readme\n",
+				".github/CODEOWNERS":   "* @heaths\n",
+				".github/template.yml": "partials: shared\n",
+			},
+		},
+		{
+			name:     "override",
+			conflict: conflictOverride,
+			want: map[string]string{
+				".gitignore":           "*.log\n",
+				"LICENSE.txt":          "base license",
+				"README.md":            "base readme\n",
+				".github/CODEOWNERS":   "* @heaths\n",
+				".github/template.yml": "partials: shared\n",
+			},
+		},
+		{
+			name:     "append",
+			conflict: conflictAppend,
+			want: map[string]string{
+				".gitignore":           "bin/\n*.log\n",
+				"LICENSE.txt":          "base license",
+				"README.md":            "template readme\nbase readme\n",
+				".github/CODEOWNERS":   "* @heaths\n",
+				".github/template.yml": "partials: shared\n",
+			},
+		},
+	}
+
+	write := func(t *testing.T, root string, files map[string]string) {
+		for name, content := range files {
+			path := filepath.Join(root, name)
+			require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+			require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		}
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := t.TempDir()
+			write(t, src, map[string]string{
+				".git/HEAD":            "ref: refs/heads/main",
+				".gitignore":           "*.log\n",
+				".github/CODEOWNERS":   "* @heaths\n",
+				".github/template.yml": "partials: base\n",
+				"LICENSE.txt":          "base license",
+				"README.md":            "base readme\n",
+			})
+
+			dst := t.TempDir()
+			write(t, dst, map[string]string{
+				".gitignore":           "bin/",
+				".github/template.yml": "partials: shared\n",
+				"README.md":            "template readme\n",
+			})
+
+			opts := &applyOptions{
+				GlobalOptions: &GlobalOptions{},
+			}
+			err := overlay(src, dst, partialsDirs{}, tt.conflict, opts)
+			require.NoError(t, err)
+
+			_, err = os.Stat(filepath.Join(dst, ".git"))
+			assert.True(t, os.IsNotExist(err), ".git should not be overlaid")
+
+			for name, want := range tt.want {
+				got, err := os.ReadFile(filepath.Join(dst, name))
+				require.NoError(t, err)
+				assert.Equal(t, want, string(got), name)
+			}
+		})
+	}
+}
+
+func TestDependency_validate(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, dependency{Template: "heaths/template-base"}.validate())
+	assert.NoError(t, dependency{Template: "heaths/template-base", Conflict: "append"}.validate())
+	assert.Error(t, dependency{}.validate())
+	assert.Error(t, dependency{Template: "heaths/template-base", Conflict: "merge"}.validate())
+}
+
+func TestComposeDependencies(t *testing.T) {
+	write := func(t *testing.T, root string, files map[string]string) {
+		for name, content := range files {
+			path := filepath.Join(root, name)
+			require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+			require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		}
+	}
+
+	templates := map[string]map[string]string{
+		"heaths/template-a": {
+			".github/template.yml": "dependencies:\n- template: heaths/template-b\n- template: heaths/template-root\n",
+			"a.txt":                "a",
+			"README.md":            "a readme",
+		},
+		"heaths/template-b": {
+			".github/template.yml":       "partials: .github/partials\ndependencies:\n- template: heaths/template-a\n  ref: v1\n",
+			".github/partials/footer.md": `{{define "footer"}}b footer{{end}}`,
+			"b.txt":                      "b",
+		},
+		"heaths/template-root": {
+			"root.txt": "fetched root",
+		},
+	}
+
+	fetched := make(map[string]int)
+	fetch := fetchTemplate
+	t.Cleanup(func() { fetchTemplate = fetch })
+	fetchTemplate = func(repo repository.Repository, ref, dir string) error {
+		name := repo.Owner() + "/" + repo.Name()
+		fetched[name]++
+		files, ok := templates[name]
+		if !ok {
+			return fmt.Errorf("repository %s not found", name)
+		}
+		write(t, dir, files)
+		return nil
+	}
+
+	root := t.TempDir()
+	write(t, root, map[string]string{
+		"README.md": "root readme",
+	})
+
+	m := &manifest{
+		Partials: "shared/",
+		Dependencies: []dependency{
+			{Template: "heaths/template-a"},
+		},
+	}
+	opts := &applyOptions{
+		GlobalOptions: &GlobalOptions{},
+		origin:        "HEATHS/Template-Root",
+	}
+
+	require.NoError(t, compose(root, m, opts))
+
+	assert.Equal(t, map[string]int{
+		"heaths/template-a": 1,
+		"heaths/template-b": 1,
+	}, fetched)
+
+	want := map[string]string{
+		"README.md":        "root readme",
+		"a.txt":            "a",
+		"b.txt":            "b",
+		"shared/footer.md": `{{define "footer"}}b footer{{end}}`,
+	}
+	for name, content := range want {
+		got, err := os.ReadFile(filepath.Join(root, name))
+		require.NoError(t, err)
+		assert.Equal(t, content, string(got), name)
+	}
+
+	for _, name := range []string{"root.txt", ".github/partials/footer.md"} {
+		_, err := os.Stat(filepath.Join(root, name))
+		assert.True(t, os.IsNotExist(err), "%s should not be overlaid", name)
+	}
+}
+
+func TestPartialsDirs_rename(t *testing.T) {
+	t.Parallel()
+
+	dirs := partialsDirs{src: ".github/partials", dst: "_partials"}
+	assert.Equal(t, "_partials/footer.md", dirs.rename(".github/partials/footer.md"))
+	assert.Equal(t, ".github/partialsx/footer.md", dirs.rename(".github/partialsx/footer.md"))
+	assert.Equal(t, "README.md", dirs.rename("README.md"))
+	assert.Equal(t, "README.md", partialsDirs{}.rename("README.md"))
+}
@@ -0,0 +1,462 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cli/go-gh/pkg/api"
+	"github.com/cli/go-gh/pkg/auth"
+	"github.com/cli/go-gh/pkg/config"
+	"github.com/cli/go-gh/pkg/repository"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/heaths/gh-template/internal/git"
+)
+
+var (
+	// errNameTaken is returned if a repository with the same name already exists.
+	errNameTaken = errors.New("name already exists")
+
+	// errPermission is returned if the user cannot read the template or create repositories for the owner.
+	errPermission = errors.New("insufficient permissions")
+
+	// errNotTemplate is returned if the template repository is not marked as a template.
+	errNotTemplate = errors.New("not a template repository")
+)
+
+// How long to wait for a generated repository to become available.
+var (
+	generateInterval = time.Second
+	generateTimeout  = time.Minute
+)
+
+type generatedRepository struct {
+	Owner    string
+	Name     string
+	CloneURL string
+	SSHURL   string
+}
+
+// generateRepository creates a new repository from the template and waits for its contents to become available.
+func generateRepository(opts *cloneOptions) (repo generatedRepository, err error) {
+	template, err := repository.Parse(opts.template)
+	if err != nil {
+		return
+	}
+
+	client, err := opts.restClient(template.Host())
+	if err != nil {
+		return
+	}
+
+	templatePath := fmt.Sprintf("repos/%s/%s", template.Owner(), template.Name())
+
+	var info struct {
+		IsTemplate bool `json:"is_template"`
+	}
+	if err = client.Get(templatePath, &info); err != nil {
+		var httpErr api.HTTPError
+		if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound {
+			return repo, fmt.Errorf("%w: cannot read template %s", errPermission, opts.template)
+		}
+		return repo, fmt.Errorf("failed to get template %s: %w", opts.template, err)
+	}
+	if !info.IsTemplate {
+		return repo, fmt.Errorf("%w: %s", errNotTemplate, opts.template)
+	}
+
+	owner, err := validateOwner(client, opts)
+	if err != nil {
+		return
+	}
+
+	body := map[string]any{
+		"owner":                owner,
+		"name":                 opts.name,
+		"include_all_branches": opts.includeAllBranches,
+		"private":              opts.private || opts.internal,
+	}
+	if opts.description != "" {
+		body["description"] = opts.description
+	}
+
+	var created struct {
+		Name     string
+		CloneURL string `json:"clone_url"`
+		SSHURL   string `json:"ssh_url"`
+		Owner    struct {
+			Login string
+		}
+	}
+
+	opts.logVerbose("generating %s/%s from %s", owner, opts.name, opts.template)
+	if err = send(client, http.MethodPost, templatePath+"/generate", body, &created); err != nil {
+		var httpErr api.HTTPError
+		if errors.As(err, &httpErr) {
+			switch {
+			case httpErr.StatusCode == http.StatusUnprocessableEntity && strings.Contains(strings.ToLower(httpErr.Message), "already exists"):
+				return generatedRepository{Owner: owner, Name: opts.name}, fmt.Errorf("%w: %s/%s", errNameTaken, owner, opts.name)
+			case httpErr.StatusCode == http.StatusForbidden || httpErr.StatusCode == http.StatusNotFound:
+				return repo, fmt.Errorf("%w: cannot create repositories for %s", errPermission, owner)
+			}
+		}
+		return repo, fmt.Errorf("failed to create repository %s/%s: %w", owner, opts.name, err)
+	}
+
+	repo = generatedRepository{
+		Owner:    created.Owner.Login,
+		Name:     created.Name,
+		CloneURL: created.CloneURL,
+		SSHURL:   created.SSHURL,
+	}
+	path := fmt.Sprintf("repos/%s/%s", repo.Owner, repo.Name)
+
+	// Set any properties not supported when generating a repository.
+	settings := make(map[string]any)
+	if opts.homepage != "" {
+		settings["homepage"] = opts.homepage
+	}
+	if opts.disableIssues {
+		settings["has_issues"] = false
+	}
+	if opts.disableWiki {
+		settings["has_wiki"] = false
+	}
+	if opts.internal {
+		settings["visibility"] = "internal"
+	}
+	if len(settings) > 0 {
+		if err = send(client, http.MethodPatch, path, settings, nil); err != nil {
+			return repo, fmt.Errorf("failed to update repository %s/%s: %w", repo.Owner, repo.Name, err)
+		}
+	}
+
+	// Contents are generated asynchronously and the repository is empty until then.
+	deadline := time.Now().Add(generateTimeout)
+	for {
+		var commits []struct{}
+		err = client.Get(path+"/commits?per_page=1", &commits)
+		if err == nil && len(commits) > 0 {
+			return repo, nil
+		}
+
+		var httpErr api.HTTPError
+		if err != nil && !(errors.As(err, &httpErr) && (httpErr.StatusCode == http.StatusConflict || httpErr.StatusCode == http.StatusNotFound)) {
+			return repo, fmt.Errorf("failed to get repository %s/%s: %w", repo.Owner, repo.Name, err)
+		}
+
+		if time.Now().After(deadline) {
+			return repo, fmt.Errorf("timed out waiting for repository %s/%s to be generated", repo.Owner, repo.Name)
+		}
+
+		opts.logVerbose("waiting for repository %s/%s to be generated", repo.Owner, repo.Name)
+		time.Sleep(generateInterval)
+	}
+}
+
+// existingRepository returns repo if it was already generated from the template e.g., if a previous clone failed
+// before the repository was cloned, and the user confirms they want to clone it and continue.
+func existingRepository(repo generatedRepository, opts *cloneOptions) (generatedRepository, error) {
+	template, err := repository.Parse(opts.template)
+	if err != nil {
+		return repo, err
+	}
+
+	client, err := opts.restClient(template.Host())
+	if err != nil {
+		return repo, err
+	}
+
+	var existing struct {
+		Name     string
+		CloneURL string `json:"clone_url"`
+		SSHURL   string `json:"ssh_url"`
+		Owner    struct {
+			Login string
+		}
+		TemplateRepository *struct {
+			FullName string `json:"full_name"`
+		} `json:"template_repository"`
+	}
+	if err = client.Get(fmt.Sprintf("repos/%s/%s", repo.Owner, repo.Name), &existing); err != nil {
+		return repo, fmt.Errorf("failed to get repository %s/%s: %w", repo.Owner, repo.Name, err)
+	}
+
+	name := existing.Owner.Login + "/" + existing.Name
+	if existing.TemplateRepository == nil || !strings.EqualFold(existing.TemplateRepository.FullName, template.Owner()+"/"+template.Name()) {
+		return repo, fmt.Errorf("%w: %s", errNameTaken, name)
+	}
+	if !opts.isInteractive() {
+		return repo, fmt.Errorf("%w: %s was already created from %s; run the same command in a terminal to clone it and continue", errNameTaken, name, opts.template)
+	}
+
+	fmt.Fprintf(opts.Console.Stderr(), "\033[32m%s was already created from %s. Clone it and continue? \033[90m[y/N]\033[0m: ", name, opts.template)
+	answer, err := bufio.NewReader(opts.stdin()).ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && answer != "") {
+		return repo, err
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return generatedRepository{
+			Owner:    existing.Owner.Login,
+			Name:     existing.Name,
+			CloneURL: existing.CloneURL,
+			SSHURL:   existing.SSHURL,
+		}, nil
+	default:
+		return repo, fmt.Errorf("%w: %s", errNameTaken, name)
+	}
+}
+
+// resolveVisibility prompts for the visibility of the new repository if it does not already exist.
+// If the repository already exists e.g., generated by a previous clone, it keeps its visibility and errNameTaken is returned.
+func resolveVisibility(opts *cloneOptions) (repo generatedRepository, err error) {
+	template, err := repository.Parse(opts.template)
+	if err != nil {
+		return
+	}
+
+	client, err := opts.restClient(template.Host())
+	if err != nil {
+		return
+	}
+
+	owner := opts.owner
+	if owner == "" {
+		var user struct {
+			Login string
+		}
+		if err = client.Get("user", &user); err != nil {
+			return repo, fmt.Errorf("failed to get current user: %w", err)
+		}
+		owner = user.Login
+	}
+
+	var existing struct{}
+	if err = client.Get(fmt.Sprintf("repos/%s/%s", owner, opts.name), &existing); err == nil {
+		return generatedRepository{Owner: owner, Name: opts.name}, fmt.Errorf("%w: %s/%s", errNameTaken, owner, opts.name)
+	}
+	var httpErr api.HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusNotFound {
+		return repo, fmt.Errorf("failed to get repository %s/%s: %w", owner, opts.name, err)
+	}
+
+	if !opts.isInteractive() {
+		return repo, fmt.Errorf("--public, --private, or --internal required when not running interactively")
+	}
+
+	return repo, promptVisibility(opts)
+}
+
+// validateOwner returns the owner of the new repository, or the current user if no owner was specified,
+// and verifies the current user can create repositories for an organization before anything is created.
+func validateOwner(client api.RESTClient, opts *cloneOptions) (string, error) {
+	var user struct {
+		Login string
+	}
+	if err := client.Get("user", &user); err != nil {
+		return "", fmt.Errorf("failed to get current user: %w", err)
+	}
+	if opts.owner == "" || strings.EqualFold(opts.owner, user.Login) {
+		return user.Login, nil
+	}
+
+	var membership struct {
+		State string
+		Role  string
+	}
+	if err := client.Get("user/memberships/orgs/"+opts.owner, &membership); err != nil {
+		var httpErr api.HTTPError
+		if errors.As(err, &httpErr) && (httpErr.StatusCode == http.StatusForbidden || httpErr.StatusCode == http.StatusNotFound) {
+			// Memberships are only found for organizations, so check whether the owner is another user instead.
+			var owner struct {
+				Type string
+			}
+			if err := client.Get("users/"+opts.owner, &owner); err != nil {
+				if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound {
+					return "", fmt.Errorf("%w: owner %s not found", errPermission, opts.owner)
+				}
+				return "", fmt.Errorf("failed to get owner %s: %w", opts.owner, err)
+			}
+			if owner.Type == "User" {
+				return "", fmt.Errorf("%w: cannot create repositories for another user account %s", errPermission, opts.owner)
+			}
+			return "", fmt.Errorf("%w: %s is not a member of %s", errPermission, user.Login, opts.owner)
+		}
+		return "", fmt.Errorf("failed to get membership in %s: %w", opts.owner, err)
+	}
+	if membership.State != "active" {
+		return "", fmt.Errorf("%w: membership of %s in %s is %s", errPermission, user.Login, opts.owner, membership.State)
+	}
+	if membership.Role == "admin" {
+		return opts.owner, nil
+	}
+
+	// Only members of the organization can see which repositories members can create.
+	var org struct {
+		MembersCanCreateRepositories         *bool `json:"members_can_create_repositories"`
+		MembersCanCreatePublicRepositories   *bool `json:"members_can_create_public_repositories"`
+		MembersCanCreatePrivateRepositories  *bool `json:"members_can_create_private_repositories"`
+		MembersCanCreateInternalRepositories *bool `json:"members_can_create_internal_repositories"`
+	}
+	if err := client.Get("orgs/"+opts.owner, &org); err != nil {
+		return "", fmt.Errorf("failed to get organization %s: %w", opts.owner, err)
+	}
+
+	allowed := org.MembersCanCreateRepositories
+	switch {
+	case opts.internal && org.MembersCanCreateInternalRepositories != nil:
+		allowed = org.MembersCanCreateInternalRepositories
+	case opts.private && org.MembersCanCreatePrivateRepositories != nil:
+		allowed = org.MembersCanCreatePrivateRepositories
+	case opts.public && org.MembersCanCreatePublicRepositories != nil:
+		allowed = org.MembersCanCreatePublicRepositories
+	}
+	if allowed != nil && !*allowed {
+		visibility := opts.visibility()
+		if visibility != "" {
+			visibility += " "
+		}
+		return "", fmt.Errorf("%w: members cannot create %srepositories for %s", errPermission, visibility, opts.owner)
+	}
+
+	return opts.owner, nil
+}
+
+// cloneRepository clones the generated repository into dir.
+func cloneRepository(repo generatedRepository, dir string, opts *cloneOptions) error {
+	template, err := repository.Parse(opts.template)
+	if err != nil {
+		return err
+	}
+
+	// Like `gh repo create --clone`, clone using SSH if configured.
+	url := repo.CloneURL
+	if repo.SSHURL != "" && gitProtocol(template.Host()) == "ssh" {
+		url = repo.SSHURL
+	}
+
+	cloneOpts := &gogit.CloneOptions{
+		URL:        url,
+		RemoteName: opts.remoteName(),
+		Auth:       opts.gitAuth(url, template.Host()),
+	}
+
+	opts.logVerbose("cloning %s into %s", url, dir)
+	if _, err = gogit.PlainClone(dir, false, cloneOpts); err != nil {
+		return fmt.Errorf("failed to clone %s/%s: %w", repo.Owner, repo.Name, err)
+	}
+
+	return nil
+}
+
+// commitRepository commits all changes in the repository cloned to dir using the git author identity.
+func commitRepository(dir string, opts *cloneOptions) error {
+	user, err := git.User(dir)
+	if err != nil {
+		return fmt.Errorf("failed to get git user: %w", err)
+	}
+	if user.Name == "" || user.Email == "" {
+		return fmt.Errorf("git user.name and user.email are required to commit formatted templates")
+	}
+
+	committed, err := git.Commit(dir, "Format template "+opts.template, user)
+	if err != nil {
+		return fmt.Errorf("failed to commit formatted templates: %w", err)
+	}
+	if !committed {
+		opts.logVerbose("no changes to commit")
+	}
+
+	return nil
+}
+
+// pushRepository pushes local branches of the repository cloned to dir.
+func pushRepository(dir string, opts *cloneOptions) error {
+	repo, err := gogit.PlainOpen(dir)
+	if err != nil {
+		return err
+	}
+
+	remote, err := repo.Remote(opts.remoteName())
+	if err != nil {
+		return err
+	}
+
+	var url string
+	if urls := remote.Config().URLs; len(urls) > 0 {
+		url = urls[0]
+	}
+
+	opts.logVerbose("pushing %s to %s", dir, opts.remoteName())
+	err = repo.PushContext(opts.context(), &gogit.PushOptions{
+		RemoteName: opts.remoteName(),
+		Auth:       opts.gitAuth(url, opts.Repo.Host()),
+	})
+	if err != nil && !errors.Is(err, gogit.NoErrAlreadyUpToDate) {
+		return fmt.Errorf("failed to push formatted templates: %w", err)
+	}
+
+	return nil
+}
+
+// remoteName returns the name of the remote for the new repository.
+func (opts *cloneOptions) remoteName() string {
+	if opts.remote == "" {
+		return gogit.DefaultRemoteName
+	}
+	return opts.remote
+}
+
+// gitAuth returns credentials for git operations over HTTPS on host, or nil if the user is not authenticated.
+// SSH URLs return nil to authenticate using the SSH agent.
+func (opts *cloneOptions) gitAuth(url, host string) transport.AuthMethod {
+	if ep, err := transport.NewEndpoint(url); err == nil && ep.Protocol == "ssh" {
+		return nil
+	}
+
+	token := opts.authToken
+	if token == "" {
+		token, _ = auth.TokenForHost(host)
+	}
+	if token == "" {
+		return nil
+	}
+
+	return &githttp.BasicAuth{
+		Username: "x-access-token",
+		Password: token,
+	}
+}
+
+// readConfig reads the gh configuration and can be replaced for testing.
+var readConfig = config.Read
+
+// gitProtocol returns the git_protocol configured in gh for host, or "https" by default.
+func gitProtocol(host string) string {
+	cfg, err := readConfig()
+	if err != nil {
+		return "https"
+	}
+
+	for _, keys := range [][]string{
+		{"hosts", host, "git_protocol"},
+		{"git_protocol"},
+	} {
+		if protocol, err := cfg.Get(keys); err == nil && protocol != "" {
+			return protocol
+		}
+	}
+
+	return "https"
+}
@@ -0,0 +1,544 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cli/go-gh/pkg/config"
+	"github.com/cli/go-gh/pkg/repository"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/heaths/go-console"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestGenerateRepository(t *testing.T) {
+	interval := generateInterval
+	generateInterval = 0
+	t.Cleanup(func() { generateInterval = interval })
+
+	template := func(isTemplate bool) {
+		gock.New("https://api.github.com").
+			Get("/repos/heaths/template-golang").
+			Reply(200).
+			JSON(map[string]any{"is_template": isTemplate})
+	}
+
+	user := func() {
+		gock.New("https://api.github.com").
+			Get("/user").
+			Reply(200).
+			JSON(`{"login": "heaths"}`)
+	}
+
+	member := func(role, org string) {
+		gock.New("https://api.github.com").
+			Get("/user/memberships/orgs/contoso").
+			Reply(200).
+			JSON(map[string]any{"state": "active", "role": role})
+		if org != "" {
+			gock.New("https://api.github.com").
+				Get("/orgs/contoso").
+				Reply(200).
+				JSON(org)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		opts       cloneOptions
+		mocks      func()
+		want       generatedRepository
+		wantErr    error
+		wantErrMsg string
+	}{
+		{
+			name: "generated",
+			opts: cloneOptions{
+				name:               "example",
+				description:        "An example",
+				homepage:           "https://example.com",
+				disableWiki:        true,
+				internal:           true,
+				includeAllBranches: true,
+			},
+			mocks: func() {
+				template(true)
+				user()
+				gock.New("https://api.github.com").
+					Post("/repos/heaths/template-golang/generate").
+					BodyString(`{
+						"owner": "heaths",
+						"name": "example",
+						"description": "An example",
+						"include_all_branches": true,
+						"private": true
+					}`).
+					Reply(201).
+					JSON(`{
+						"name": "example",
+						"owner": {"login": "heaths"},
+						"clone_url": "https://github.com/heaths/example.git",
+						"ssh_url": "git@github.com:heaths/example.git"
+					}`)
+				gock.New("https://api.github.com").
+					Patch("/repos/heaths/example").
+					BodyString(`{"homepage": "https://example.com", "has_wiki": false, "visibility": "internal"}`).
+					Reply(200).
+					JSON(`{}`)
+				gock.New("https://api.github.com").
+					Get("/repos/heaths/example/commits").
+					Reply(409).
+					JSON(`{"message": "Git Repository is empty."}`)
+				gock.New("https://api.github.com").
+					Get("/repos/heaths/example/commits").
+					Reply(200).
+					JSON(`[{"sha": "abc123"}]`)
+			},
+			want: generatedRepository{
+				Owner:    "heaths",
+				Name:     "example",
+				CloneURL: "https://github.com/heaths/example.git",
+				SSHURL:   "git@github.com:heaths/example.git",
+			},
+		},
+		{
+			name: "owner",
+			opts: cloneOptions{
+				owner: "contoso",
+				name:  "example",
+			},
+			mocks: func() {
+				template(true)
+				user()
+				member("member", `{"members_can_create_repositories": true}`)
+				gock.New("https://api.github.com").
+					Post("/repos/heaths/template-golang/generate").
+					BodyString(`{"owner": "contoso", "name": "example", "include_all_branches": false, "private": false}`).
+					Reply(201).
+					JSON(`{
+						"name": "example",
+						"owner": {"login": "contoso"},
+						"clone_url": "https://github.com/contoso/example.git"
+					}`)
+				gock.New("https://api.github.com").
+					Get("/repos/contoso/example/commits").
+					Reply(200).
+					JSON(`[{"sha": "abc123"}]`)
+			},
+			want: generatedRepository{
+				Owner:    "contoso",
+				Name:     "example",
+				CloneURL: "https://github.com/contoso/example.git",
+			},
+		},
+		{
+			name: "name taken",
+			opts: cloneOptions{
+				owner: "Heaths",
+				name:  "example",
+			},
+			mocks: func() {
+				template(true)
+				user()
+				gock.New("https://api.github.com").
+					Post("/repos/heaths/template-golang/generate").
+					Reply(422).
+					JSON(`{
+						"message": "Unprocessable Entity",
+						"errors": [{"message": "Name already exists on this account"}]
+					}`)
+			},
+			wantErr: errNameTaken,
+		},
+		{
+			name: "permission",
+			opts: cloneOptions{
+				owner: "contoso",
+				name:  "example",
+			},
+			mocks: func() {
+				template(true)
+				user()
+				member("admin", "")
+				gock.New("https://api.github.com").
+					Post("/repos/heaths/template-golang/generate").
+					Reply(403).
+					JSON(`{"message": "Resource not accessible by integration"}`)
+			},
+			wantErr: errPermission,
+		},
+		{
+			name: "not member",
+			opts: cloneOptions{
+				owner: "contoso",
+				name:  "example",
+			},
+			mocks: func() {
+				template(true)
+				user()
+				gock.New("https://api.github.com").
+					Get("/user/memberships/orgs/contoso").
+					Reply(404).
+					JSON(`{"message": "Not Found"}`)
+				gock.New("https://api.github.com").
+					Get("/users/contoso").
+					Reply(200).
+					JSON(`{"login": "contoso", "type": "Organization"}`)
+			},
+			wantErr:    errPermission,
+			wantErrMsg: "heaths is not a member of contoso",
+		},
+		{
+			name: "another user",
+			opts: cloneOptions{
+				owner: "octocat",
+				name:  "example",
+			},
+			mocks: func() {
+				template(true)
+				user()
+				gock.New("https://api.github.com").
+					Get("/user/memberships/orgs/octocat").
+					Reply(404).
+					JSON(`{"message": "Not Found"}`)
+				gock.New("https://api.github.com").
+					Get("/users/octocat").
+					Reply(200).
+					JSON(`{"login": "octocat", "type": "User"}`)
+			},
+			wantErr:    errPermission,
+			wantErrMsg: "cannot create repositories for another user account octocat",
+		},
+		{
+			name: "owner not found",
+			opts: cloneOptions{
+				owner: "missing",
+				name:  "example",
+			},
+			mocks: func() {
+				template(true)
+				user()
+				gock.New("https://api.github.com").
+					Get("/user/memberships/orgs/missing").
+					Reply(404).
+					JSON(`{"message": "Not Found"}`)
+				gock.New("https://api.github.com").
+					Get("/users/missing").
+					Reply(404).
+					JSON(`{"message": "Not Found"}`)
+			},
+			wantErr:    errPermission,
+			wantErrMsg: "owner missing not found",
+		},
+		{
+			name: "pending member",
+			opts: cloneOptions{
+				owner: "contoso",
+				name:  "example",
+			},
+			mocks: func() {
+				template(true)
+				user()
+				gock.New("https://api.github.com").
+					Get("/user/memberships/orgs/contoso").
+					Reply(200).
+					JSON(`{"state": "pending", "role": "member"}`)
+			},
+			wantErr: errPermission,
+		},
+		{
+			name: "members cannot create private",
+			opts: cloneOptions{
+				owner:   "contoso",
+				name:    "example",
+				private: true,
+			},
+			mocks: func() {
+				template(true)
+				user()
+				member("member", `{"members_can_create_repositories": true, "members_can_create_private_repositories": false}`)
+			},
+			wantErr: errPermission,
+		},
+		{
+			name: "template not found",
+			opts: cloneOptions{
+				name: "example",
+			},
+			mocks: func() {
+				gock.New("https://api.github.com").
+					Get("/repos/heaths/template-golang").
+					Reply(404).
+					JSON(`{"message": "Not Found"}`)
+			},
+			wantErr: errPermission,
+		},
+		{
+			name: "not template",
+			opts: cloneOptions{
+				name: "example",
+			},
+			mocks: func() {
+				template(false)
+			},
+			wantErr: errNotTemplate,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(gock.Off)
+			tt.mocks()
+
+			opts := tt.opts
+			opts.template = "heaths/template-golang"
+			opts.GlobalOptions = &GlobalOptions{
+				Console:   console.Fake(),
+				authToken: "***",
+				host:      "github.com",
+			}
+
+			got, err := generateRepository(&opts)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				if tt.wantErrMsg != "" {
+					assert.ErrorContains(t, err, tt.wantErrMsg)
+				}
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+			assert.True(t, gock.IsDone(), pendingMocks(gock.Pending()))
+		})
+	}
+}
+
+func TestCloneRepository(t *testing.T) {
+	source := t.TempDir()
+	repo, err := gogit.PlainInit(source, false)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(source, "README.md"), []byte("# Example\n"), 0o644))
+	worktree, err := repo.Worktree()
+	require.NoError(t, err)
+	_, err = worktree.Add("README.md")
+	require.NoError(t, err)
+	_, err = worktree.Commit("Initial commit", &gogit.CommitOptions{
+		Author: &object.Signature{Name: "Test User", Email: "test@domain.com", When: time.Now()},
+	})
+	require.NoError(t, err)
+
+	opts := &cloneOptions{
+		template: "heaths/template-golang",
+		remote:   "upstream",
+	}
+	opts.GlobalOptions = &GlobalOptions{
+		Console: console.Fake(),
+	}
+
+	dir := filepath.Join(t.TempDir(), "example")
+	err = cloneRepository(generatedRepository{Owner: "heaths", Name: "example", CloneURL: source}, dir, opts)
+	require.NoError(t, err)
+
+	assert.FileExists(t, filepath.Join(dir, "README.md"))
+
+	cloned, err := gogit.PlainOpen(dir)
+	require.NoError(t, err)
+	_, err = cloned.Remote("upstream")
+	assert.NoError(t, err)
+}
+
+func TestCommitAndPushRepository(t *testing.T) {
+	source := t.TempDir()
+	repo, err := gogit.PlainInit(source, false)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(source, "README.md"), []byte("# {{param \"name\"}}\n"), 0o644))
+	worktree, err := repo.Worktree()
+	require.NoError(t, err)
+	_, err = worktree.Add("README.md")
+	require.NoError(t, err)
+	_, err = worktree.Commit("Initial commit", &gogit.CommitOptions{
+		Author: &object.Signature{Name: "Test User", Email: "test@domain.com", When: time.Now()},
+	})
+	require.NoError(t, err)
+
+	remote := t.TempDir()
+	_, err = gogit.PlainClone(remote, true, &gogit.CloneOptions{URL: source})
+	require.NoError(t, err)
+
+	repoInfo, err := repository.Parse("heaths/example")
+	require.NoError(t, err)
+
+	opts := &cloneOptions{
+		template: "heaths/template-golang",
+	}
+	opts.GlobalOptions = &GlobalOptions{
+		Console: console.Fake(),
+		Repo:    repoInfo,
+	}
+
+	dir := filepath.Join(t.TempDir(), "example")
+	err = cloneRepository(generatedRepository{Owner: "heaths", Name: "example", CloneURL: remote}, dir, opts)
+	require.NoError(t, err)
+
+	cloned, err := gogit.PlainOpen(dir)
+	require.NoError(t, err)
+	cfg, err := cloned.Config()
+	require.NoError(t, err)
+	cfg.User.Name = "Test User"
+	cfg.User.Email = "test@domain.com"
+	require.NoError(t, cloned.SetConfig(cfg))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("# example\n"), 0o644))
+	require.NoError(t, commitRepository(dir, opts))
+	require.NoError(t, pushRepository(dir, opts))
+
+	// Pushing again is not an error.
+	require.NoError(t, pushRepository(dir, opts))
+
+	pushed, err := gogit.PlainOpen(remote)
+	require.NoError(t, err)
+	head, err := pushed.Head()
+	require.NoError(t, err)
+	commit, err := pushed.CommitObject(head.Hash())
+	require.NoError(t, err)
+	assert.Equal(t, "Format template heaths/template-golang", commit.Message)
+	assert.Equal(t, "test@domain.com", commit.Author.Email)
+}
+
+func TestExistingRepository(t *testing.T) {
+	existing := func(template string) func() {
+		return func() {
+			body := map[string]any{
+				"name":      "example",
+				"clone_url": "https://github.com/heaths/example.git",
+				"owner":     map[string]any{"login": "heaths"},
+			}
+			if template != "" {
+				body["template_repository"] = map[string]any{"full_name": template}
+			}
+			gock.New("https://api.github.com").
+				Get("/repos/heaths/example").
+				Reply(200).
+				JSON(body)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		mocks       func()
+		stdin       string
+		interactive bool
+		want        generatedRepository
+		wantErr     string
+	}{
+		{
+			name:        "continue",
+			mocks:       existing("Heaths/Template-Golang"),
+			stdin:       "y\n",
+			interactive: true,
+			want: generatedRepository{
+				Owner:    "heaths",
+				Name:     "example",
+				CloneURL: "https://github.com/heaths/example.git",
+			},
+		},
+		{
+			name:        "declined",
+			mocks:       existing("heaths/template-golang"),
+			stdin:       "\n",
+			interactive: true,
+			wantErr:     "name already exists: heaths/example",
+		},
+		{
+			name:    "not interactive",
+			mocks:   existing("heaths/template-golang"),
+			wantErr: "name already exists: heaths/example was already created from heaths/template-golang; run the same command in a terminal to clone it and continue",
+		},
+		{
+			name:        "other template",
+			mocks:       existing("heaths/template-rust"),
+			interactive: true,
+			wantErr:     "name already exists: heaths/example",
+		},
+		{
+			name:        "no template",
+			mocks:       existing(""),
+			interactive: true,
+			wantErr:     "name already exists: heaths/example",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(gock.Off)
+			tt.mocks()
+
+			opts := &cloneOptions{
+				template: "heaths/template-golang",
+			}
+			opts.GlobalOptions = &GlobalOptions{
+				Console: console.Fake(
+					console.WithStdin(bytes.NewBufferString(tt.stdin)),
+					console.WithStdinTTY(tt.interactive),
+					console.WithStderrTTY(tt.interactive),
+				),
+				authToken: "***",
+				host:      "github.com",
+			}
+
+			repo := generatedRepository{Owner: "heaths", Name: "example"}
+			got, err := existingRepository(repo, opts)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				assert.ErrorIs(t, err, errNameTaken)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+			assert.True(t, gock.IsDone(), pendingMocks(gock.Pending()))
+		})
+	}
+}
+
+func TestGitProtocol(t *testing.T) {
+	original := readConfig
+	t.Cleanup(func() { readConfig = original })
+
+	readConfig = func() (*config.Config, error) {
+		return config.ReadFromString(`
+git_protocol: ssh
+hosts:
+  ghes.contoso.com:
+    git_protocol: https
+`), nil
+	}
+	assert.Equal(t, "ssh", gitProtocol("github.com"))
+	assert.Equal(t, "https", gitProtocol("ghes.contoso.com"))
+
+	readConfig = func() (*config.Config, error) {
+		return config.ReadFromString(""), nil
+	}
+	assert.Equal(t, "https", gitProtocol("github.com"))
+}
+
+func TestGitAuth(t *testing.T) {
+	t.Parallel()
+
+	opts := &cloneOptions{}
+	opts.GlobalOptions = &GlobalOptions{
+		authToken: "***",
+	}
+
+	assert.NotNil(t, opts.gitAuth("https://github.com/heaths/example.git", "github.com"))
+	assert.Nil(t, opts.gitAuth("git@github.com:heaths/example.git", "github.com"))
+	assert.Nil(t, opts.gitAuth("ssh://git@github.com/heaths/example.git", "github.com"))
+}
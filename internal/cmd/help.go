@@ -18,28 +18,66 @@ git.email	Configured Git user.email
 github.host	The host e.g., github.com
 github.owner	Owning user or organization
 github.repo	Name of the repository
+github.login	Login of the authenticated user
+github.name	Display name of the authenticated user
+github.ownerName	Display name of the owning user or organization
+system.os	Current operating system e.g., linux
+system.arch	Current architecture e.g., amd64
+env.NAME	Environment variable NAME passed to --env
 `
 
-const functions = `
+const cloneVariables = `
+github.description	Description of the new repository
+github.homepage	Home page URL of the new repository
+github.visibility	Visibility of the new repository e.g., public
+github.defaultBranch	Default branch of the new repository
+`
+
+// sourceVariables describe the template repository and are set by clone and by apply with --template.
+const sourceVariables = `
+template.owner	Owning user or organization of the template repository
+template.repo	Name of the template repository
+template.ref	Default branch of the template repository
+template.sha	Commit SHA of the template repository ref
+`
+
+const templateFunctions = `
 param name [default [prompt]]	Replace name with value, optionally prompting with default
 pluralize count thing	Pluralize thing based on count
 lowercase string	Make string lowercase
 titlecase string	Make string titlecase
 uppercase string	Make string uppercase
 replace from to source	Replace from with to in source
+camelcase string	Make string camelCase
+pascalcase string	Make string PascalCase
+snakecase string	Make string snake_case
+kebabcase string	Make string kebab-case
+screamingcase string	Make string SCREAMING_CASE
+trim string	Trim leading and trailing whitespace from string
+trimPrefix prefix string	Trim prefix from string
+trimSuffix suffix string	Trim suffix from string
+split sep string	Split string into a list separated by sep
+join sep list	Join list into a string separated by sep
+indent spaces string	Indent every line of string by spaces
+nindent spaces string	Indent every line of string by spaces after a newline
+regexReplace pattern replacement string	Replace regular expression pattern with replacement in string
+contains substr string	Whether string contains substr
+default default value	Return default if value is empty
 date	Get UTC date
 date.Local	Get local date
 date.Year	Get year from date
 date.Format layout	Format date based on layout like time.Format()
 true	Returns true
 false	Returns false
+license [id]	Render SPDX license id, or the license passed to --license
+spdx [id]	SPDX-License-Identifier header for license id, or the license passed to --license
 deleteFile	Deletes the current file, or a list of file names relative to the root
 `
 
-func annotations() map[string]string {
+func annotations(variables string) map[string]string {
 	return map[string]string{
 		"help:variables": variables,
-		"help:functions": functions,
+		"help:functions": templateFunctions,
 	}
 }
 
@@ -0,0 +1,232 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/cli/go-gh/pkg/api"
+	"github.com/cli/go-gh/pkg/repository"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultLabels are created by GitHub in every new repository.
+var defaultLabels = []string{
+	"bug",
+	"documentation",
+	"duplicate",
+	"enhancement",
+	"good first issue",
+	"help wanted",
+	"invalid",
+	"question",
+	"wontfix",
+}
+
+type label struct {
+	Name        string `yaml:"name" json:"name"`
+	Color       string `yaml:"color" json:"color"`
+	Description string `yaml:"description" json:"description"`
+}
+
+type labelOptions struct {
+	// mode is "merge" to create and update labels, or "replace" to also delete any other labels.
+	mode           string
+	deleteDefaults bool
+
+	// include and exclude are case-insensitive glob patterns of label names to sync.
+	include []string
+	exclude []string
+}
+
+// validate returns an error if options are invalid.
+func (l labelOptions) validate() error {
+	if l.mode != "merge" && l.mode != "replace" {
+		return fmt.Errorf(`invalid --labels-mode %q; expected "merge" or "replace"`, l.mode)
+	}
+
+	for _, patterns := range [][]string{l.include, l.exclude} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid label pattern %q: %w", pattern, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// matches returns true if name matches any include pattern, if any, and no exclude patterns.
+func (l labelOptions) matches(name string) bool {
+	name = strings.ToLower(name)
+	match := func(patterns []string) bool {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(strings.ToLower(pattern), name); ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(l.include) > 0 && !match(l.include) {
+		return false
+	}
+
+	return !match(l.exclude)
+}
+
+type labelSummary struct {
+	created int
+	updated int
+	deleted int
+}
+
+func (s labelSummary) String() string {
+	return fmt.Sprintf("Created %d, updated %d, and deleted %d labels", s.created, s.updated, s.deleted)
+}
+
+// readLabels reads a YAML list of labels from path relative to root.
+func readLabels(root, path string) ([]label, error) {
+	content, err := os.ReadFile(filepath.Join(root, path))
+	if err != nil {
+		return nil, err
+	}
+
+	var labels []label
+	if err = yaml.Unmarshal(content, &labels); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	return labels, nil
+}
+
+// listLabels gets all labels in a repository.
+func listLabels(client api.RESTClient, repo repository.Repository) ([]label, error) {
+	var labels []label
+	for page := 1; ; page++ {
+		var items []label
+		err := client.Get(fmt.Sprintf("repos/%s/%s/labels?per_page=100&page=%d", repo.Owner(), repo.Name(), page), &items)
+		if err != nil {
+			return nil, err
+		}
+
+		labels = append(labels, items...)
+		if len(items) < 100 {
+			return labels, nil
+		}
+	}
+}
+
+// syncLabels creates, updates, and optionally deletes labels in the current repository to match source.
+func syncLabels(source []label, lopts labelOptions, opts *applyOptions) (summary labelSummary, err error) {
+	client, err := opts.restClient(opts.Repo.Host())
+	if err != nil {
+		return
+	}
+
+	existing, err := listLabels(client, opts.Repo)
+	if err != nil {
+		return summary, fmt.Errorf("failed to list labels: %w", err)
+	}
+
+	targets := make(map[string]label, len(existing))
+	for _, l := range existing {
+		targets[strings.ToLower(l.Name)] = l
+	}
+
+	path := fmt.Sprintf("repos/%s/%s/labels", opts.Repo.Owner(), opts.Repo.Name())
+	keep := make(map[string]bool, len(source))
+
+	for _, l := range source {
+		if !lopts.matches(l.Name) {
+			continue
+		}
+		l.Color = strings.ToLower(strings.TrimPrefix(l.Color, "#"))
+
+		key := strings.ToLower(l.Name)
+		keep[key] = true
+
+		target, ok := targets[key]
+		if !ok {
+			opts.logVerbose("creating label %q", l.Name)
+			if err = send(client, http.MethodPost, path, l, nil); err != nil {
+				return summary, fmt.Errorf("failed to create label %q: %w", l.Name, err)
+			}
+			summary.created++
+			continue
+		}
+
+		if target.Name == l.Name && strings.EqualFold(target.Color, l.Color) && target.Description == l.Description {
+			continue
+		}
+
+		opts.logVerbose("updating label %q", target.Name)
+		body := map[string]string{
+			"new_name":    l.Name,
+			"color":       l.Color,
+			"description": l.Description,
+		}
+		if err = send(client, http.MethodPatch, path+"/"+url.PathEscape(target.Name), body, nil); err != nil {
+			return summary, fmt.Errorf("failed to update label %q: %w", target.Name, err)
+		}
+		summary.updated++
+	}
+
+	for _, l := range existing {
+		if keep[strings.ToLower(l.Name)] {
+			continue
+		}
+
+		if lopts.mode == "replace" && lopts.matches(l.Name) || lopts.deleteDefaults && isDefaultLabel(l.Name) {
+			opts.logVerbose("deleting label %q", l.Name)
+			if err = client.Delete(path+"/"+url.PathEscape(l.Name), nil); err != nil {
+				return summary, fmt.Errorf("failed to delete label %q: %w", l.Name, err)
+			}
+			summary.deleted++
+		}
+	}
+
+	return
+}
+
+func isDefaultLabel(name string) bool {
+	for _, l := range defaultLabels {
+		if strings.EqualFold(l, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// cloneLabels syncs labels declared by the template manifest, or from the template repository, into the current repository.
+func cloneLabels(opts *cloneOptions) (summary labelSummary, err error) {
+	var source []label
+	if opts.labels {
+		if opts.manifest != nil && opts.manifest.Labels != "" {
+			source = opts.manifest.labels
+		} else {
+			repo, err := repository.Parse(opts.template)
+			if err != nil {
+				return summary, err
+			}
+
+			client, err := opts.restClient(repo.Host())
+			if err != nil {
+				return summary, err
+			}
+
+			if source, err = listLabels(client, repo); err != nil {
+				return summary, fmt.Errorf("failed to list labels from %s: %w", opts.template, err)
+			}
+		}
+	}
+
+	return syncLabels(source, opts.labelOptions, &opts.applyOptions)
+}
@@ -0,0 +1,160 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/cli/go-gh/pkg/repository"
+	"github.com/heaths/go-console"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestSyncLabels(t *testing.T) {
+	existing := []label{
+		{Name: "bug", Color: "d73a4a", Description: "Something isn't working"},
+		{Name: "enhancement", Color: "a2eeef", Description: "New feature or request"},
+		{Name: "custom", Color: "ededed"},
+	}
+
+	tests := []struct {
+		name   string
+		source []label
+		opts   labelOptions
+		mocks  func()
+		want   labelSummary
+	}{
+		{
+			name: "merge",
+			source: []label{
+				{Name: "bug", Color: "#D73A4A", Description: "Something isn't working"},
+				{Name: "feature", Color: "00ff00"},
+			},
+			opts: labelOptions{mode: "merge"},
+			mocks: func() {
+				gock.New("https://api.github.com").
+					Post("/repos/heaths/example/labels").
+					BodyString(`{"name": "feature", "color": "00ff00", "description": ""}`).
+					Reply(201).
+					JSON(`{}`)
+			},
+			want: labelSummary{created: 1},
+		},
+		{
+			name: "replace",
+			source: []label{
+				{Name: "Bug", Color: "ff0000", Description: "Something broke"},
+				{Name: "feature", Color: "00ff00"},
+				{Name: "custom-ignored", Color: "0000ff"},
+			},
+			opts: labelOptions{mode: "replace", exclude: []string{"Custom*"}},
+			mocks: func() {
+				gock.New("https://api.github.com").
+					Patch("/repos/heaths/example/labels/bug").
+					BodyString(`{"new_name": "Bug", "color": "ff0000", "description": "Something broke"}`).
+					Reply(200).
+					JSON(`{}`)
+				gock.New("https://api.github.com").
+					Post("/repos/heaths/example/labels").
+					BodyString(`{"name": "feature", "color": "00ff00", "description": ""}`).
+					Reply(201).
+					JSON(`{}`)
+				gock.New("https://api.github.com").
+					Delete("/repos/heaths/example/labels/enhancement").
+					Reply(204)
+			},
+			want: labelSummary{created: 1, updated: 1, deleted: 1},
+		},
+		{
+			name: "delete defaults",
+			source: []label{
+				{Name: "enhancement", Color: "a2eeef", Description: "New feature or request"},
+			},
+			opts: labelOptions{mode: "merge", deleteDefaults: true},
+			mocks: func() {
+				gock.New("https://api.github.com").
+					Delete("/repos/heaths/example/labels/bug").
+					Reply(204)
+			},
+			want: labelSummary{deleted: 1},
+		},
+		{
+			name:   "include",
+			source: existing,
+			opts:   labelOptions{mode: "replace", include: []string{"b*"}},
+			mocks:  func() {},
+			want:   labelSummary{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(gock.Off)
+			gock.New("https://api.github.com").
+				Get("/repos/heaths/example/labels").
+				MatchParam("page", "1").
+				Reply(200).
+				JSON(existing)
+			tt.mocks()
+
+			repo, err := repository.Parse("heaths/example")
+			require.NoError(t, err)
+
+			opts := &applyOptions{
+				GlobalOptions: &GlobalOptions{
+					Console:   console.Fake(),
+					Repo:      repo,
+					authToken: "***",
+					host:      "github.com",
+				},
+			}
+
+			got, err := syncLabels(tt.source, tt.opts, opts)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.True(t, gock.IsDone(), pendingMocks(gock.Pending()))
+		})
+	}
+}
+
+func TestLabelOptions_validate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		opts    labelOptions
+		wantErr string
+	}{
+		{
+			name: "valid",
+			opts: labelOptions{mode: "replace", include: []string{"area:*"}},
+		},
+		{
+			name:    "invalid mode",
+			opts:    labelOptions{mode: "force"},
+			wantErr: `invalid --labels-mode "force"; expected "merge" or "replace"`,
+		},
+		{
+			name:    "invalid pattern",
+			opts:    labelOptions{mode: "merge", exclude: []string{"[a-"}},
+			wantErr: `invalid label pattern "[a-": syntax error in pattern`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tt.opts.validate()
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// manifestPath is the path to an optional template manifest relative to the repository root.
+// The manifest is never processed as a template and is deleted after templates are applied.
+const manifestPath = ".github/template.yml"
+
+type manifest struct {
+	// Partials is the directory of named templates relative to the repository root.
+	Partials string `yaml:"partials"`
+
+	// Dependencies are other template repositories overlaid onto this template in declared order.
+	Dependencies []dependency `yaml:"dependencies"`
+
+	// Strategies map path patterns to how existing files are merged when applying this template
+	// onto another repository: "skip", "overwrite", "prompt", or "merge".
+	Strategies map[string]string `yaml:"strategies"`
+
+	// Repository settings applied to a new repository after it is created.
+	Repository *repositorySettings `yaml:"repository"`
+
+	// Access grants teams and collaborators permissions to a new repository.
+	Access *accessSettings `yaml:"access"`
+
+	// Protection maps branch names to branch protection rules applied to a new repository.
+	Protection map[string]map[string]any `yaml:"protection"`
+
+	// Rulesets are repository rulesets created in a new repository.
+	Rulesets []map[string]any `yaml:"rulesets"`
+
+	// Labels is the path to a YAML list of labels relative to the repository root
+	// synced instead of labels from the template repository. The file is deleted after templates are applied.
+	Labels string `yaml:"labels"`
+	labels []label
+
+	// Milestones, Issues, and Projects are created in a new repository after labels are synced.
+	Milestones []milestone `yaml:"milestones"`
+	Issues     []issue     `yaml:"issues"`
+	Projects   []project   `yaml:"projects"`
+
+	// Actions variables, environments, and required secrets created in a new repository.
+	Actions *actionsSettings `yaml:"actions"`
+}
+
+func readManifest(root string) (*manifest, error) {
+	m := &manifest{}
+
+	content, err := os.ReadFile(filepath.Join(root, manifestPath))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return m, nil
+		}
+		return nil, err
+	}
+
+	if err = yaml.Unmarshal(content, m); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", manifestPath, err)
+	}
+
+	return m, nil
+}
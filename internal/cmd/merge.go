@@ -0,0 +1,402 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cli/go-gh/pkg/auth"
+	"github.com/cli/go-gh/pkg/repository"
+	"github.com/heaths/gh-template/internal/git"
+	"github.com/heaths/gh-template/internal/template/processor"
+)
+
+const (
+	strategySkip      = "skip"
+	strategyOverwrite = "overwrite"
+	strategyPrompt    = "prompt"
+	strategyMerge     = "merge"
+)
+
+// applyTemplate fetches a template repository into a staging directory, applies templates,
+// and merges the results into root, which is a staged copy of the working tree in opts.
+func applyTemplate(root string, opts *applyOptions) error {
+	repo, err := repository.Parse(opts.source)
+	if err != nil {
+		return err
+	}
+
+	staging, err := os.MkdirTemp("", "gh-template-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+
+	src := filepath.Join(staging, repo.Name())
+	token, _ := auth.TokenForHost(repo.Host())
+	url := fmt.Sprintf("https://%s/%s/%s.git", repo.Host(), repo.Owner(), repo.Name())
+
+	opts.Console.StartProgress("Fetching template " + opts.source)
+	err = git.Clone(url, src, git.CloneOptions{
+		Depth: 1,
+		Token: token,
+	})
+	opts.Console.StopProgress()
+	if err != nil {
+		return fmt.Errorf("failed to fetch template %s: %w", opts.source, err)
+	}
+
+	if err = os.RemoveAll(filepath.Join(src, ".git")); err != nil {
+		return err
+	}
+
+	if err = templateVariables(opts.GlobalOptions, opts.source, opts.params); err != nil {
+		opts.logVerbose("failed to get template information: %v", err)
+	}
+
+	if err = applyDir(src, opts); err != nil {
+		return err
+	}
+
+	strategies, err := parseStrategies(opts.strategies, opts.manifest.Strategies)
+	if err != nil {
+		return err
+	}
+
+	return mergeTree(src, root, opts.rootDir(), strategies, opts)
+}
+
+// mergeStrategies determines how to merge files that already exist.
+type mergeStrategies struct {
+	fallback string
+	patterns []patternStrategy
+}
+
+type patternStrategy struct {
+	pattern  string
+	strategy string
+}
+
+// parseStrategies parses strategies declared by a template manifest followed by
+// command line strategies as "strategy" or "pattern=strategy". The last matching pattern wins.
+func parseStrategies(values []string, declared map[string]string) (*mergeStrategies, error) {
+	s := &mergeStrategies{
+		fallback: strategySkip,
+	}
+
+	add := func(pattern, strategy string) error {
+		switch strategy {
+		case strategySkip, strategyOverwrite, strategyPrompt, strategyMerge:
+		default:
+			return fmt.Errorf("unsupported strategy %q; expected %s, %s, %s, or %s", strategy, strategySkip, strategyOverwrite, strategyPrompt, strategyMerge)
+		}
+
+		if pattern == "" {
+			s.fallback = strategy
+			return nil
+		}
+
+		pattern = strings.TrimPrefix(filepath.ToSlash(pattern), "./")
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		s.patterns = append(s.patterns, patternStrategy{pattern: pattern, strategy: strategy})
+		return nil
+	}
+
+	// Sort declared patterns for deterministic precedence.
+	patterns := make([]string, 0, len(declared))
+	for pattern := range declared {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		if err := add(pattern, declared[pattern]); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, value := range values {
+		pattern, strategy, found := strings.Cut(value, "=")
+		if !found {
+			pattern, strategy = "", value
+		}
+		if err := add(pattern, strategy); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+func (s *mergeStrategies) strategy(name string) string {
+	strategy := s.fallback
+	for _, p := range s.patterns {
+		if matched, _ := path.Match(p.pattern, name); matched {
+			strategy = p.strategy
+		} else if matched, _ = path.Match(p.pattern, path.Base(name)); matched && !strings.Contains(p.pattern, "/") {
+			strategy = p.strategy
+		}
+	}
+	return strategy
+}
+
+// mergeTree merges all files from src into dst using strategies for files that already exist.
+// If dst is a staged copy of original, files not staged e.g., ignored files, are copied from original
+// before merging so they are not replaced; otherwise, original is empty.
+func mergeTree(src, dst, original string, strategies *mergeStrategies, opts *applyOptions) error {
+	var reader *bufio.Reader
+	return filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+
+		if name == ".git" {
+			return fs.SkipDir
+		}
+
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0o755)
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+
+		existing, err := os.ReadFile(target)
+		if os.IsNotExist(err) && original != "" {
+			if err = copyFile(filepath.Join(original, rel), target); err == nil {
+				existing, err = os.ReadFile(target)
+			}
+		}
+		if os.IsNotExist(err) {
+			opts.logVerbose("creating %q", name)
+			return os.WriteFile(target, content, info.Mode().Perm())
+		} else if err != nil {
+			return err
+		}
+
+		if bytes.Equal(content, existing) {
+			return nil
+		}
+
+		strategy := strategies.strategy(name)
+		if strategy == strategyPrompt {
+			if reader == nil {
+				reader = bufio.NewReader(opts.stdin())
+			}
+			if strategy, err = promptStrategy(reader, opts.Console.Stderr(), opts.isInteractive(), name); err != nil {
+				return err
+			}
+		}
+
+		switch strategy {
+		case strategyOverwrite:
+			opts.logVerbose("overwriting %q", name)
+			return os.WriteFile(target, content, info.Mode().Perm())
+		case strategyMerge:
+			if isIgnoreFile(name) {
+				opts.logVerbose("merging %q", name)
+				return os.WriteFile(target, mergeLines(existing, content), info.Mode().Perm())
+			}
+
+			if processor.IsBinaryContent(existing) || processor.IsBinaryContent(content) {
+				fmt.Fprintf(opts.Console.Stderr(), "Skipping existing binary file %s that cannot be merged\n", name)
+				return nil
+			}
+
+			merged, conflicts := mergeConflicts(existing, content, templateLabel(opts.source))
+			if conflicts {
+				fmt.Fprintf(opts.Console.Stderr(), "Merge conflicts in %s; resolve the conflict markers before committing\n", name)
+			}
+			opts.logVerbose("merging %q", name)
+			return os.WriteFile(target, merged, info.Mode().Perm())
+		default:
+			opts.logVerbose("skipping existing %q", name)
+			return nil
+		}
+	})
+}
+
+func promptStrategy(r *bufio.Reader, w io.Writer, isTTY bool, name string) (string, error) {
+	if !isTTY {
+		return "", fmt.Errorf("cannot prompt to merge %q", name)
+	}
+
+	for {
+		fmt.Fprintf(w, "\033[32m%s already exists. Overwrite, merge, or skip? \033[90m[o/m/S]\033[0m: ", name)
+
+		answer, err := r.ReadString('\n')
+		if err != nil && !(err == io.EOF && answer != "") {
+			return "", err
+		}
+
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "", "s", "skip":
+			return strategySkip, nil
+		case "o", "overwrite":
+			return strategyOverwrite, nil
+		case "m", "merge":
+			return strategyMerge, nil
+		}
+
+		fmt.Fprintln(w, "\033[31mExpected o, m, or s. Please try again.\033[0m")
+	}
+}
+
+// isIgnoreFile returns true if name is an ignore file e.g., .gitignore or .dockerignore,
+// in which the order of and duplicate patterns do not matter.
+func isIgnoreFile(name string) bool {
+	base := path.Base(name)
+	return strings.HasPrefix(base, ".") && strings.HasSuffix(base, "ignore") || name == ".git/info/exclude"
+}
+
+func templateLabel(source string) string {
+	if source == "" {
+		return "template"
+	}
+	return source
+}
+
+// mergeLines appends lines from content not already in existing, preserving the order of both.
+// This is only appropriate for ignore files; see mergeConflicts for all other files.
+func mergeLines(existing, content []byte) []byte {
+	eol := []byte("\n")
+	if bytes.Contains(existing, []byte("\r\n")) {
+		eol = []byte("\r\n")
+	}
+
+	lines := make(map[string]bool)
+	for _, line := range bytes.Split(existing, []byte("\n")) {
+		lines[string(bytes.TrimRight(line, "\r"))] = true
+	}
+
+	merged := existing
+	for _, line := range bytes.Split(content, []byte("\n")) {
+		line = bytes.TrimRight(line, "\r")
+		if len(line) == 0 || lines[string(line)] {
+			continue
+		}
+		lines[string(line)] = true
+
+		if len(merged) > 0 && !bytes.HasSuffix(merged, []byte("\n")) {
+			merged = append(merged, eol...)
+		}
+		merged = append(merged, line...)
+		merged = append(merged, eol...)
+	}
+
+	return merged
+}
+
+// mergeConflicts merges content into existing, keeping lines common to both and surrounding
+// differing lines with conflict markers like git. Returns true if there were any conflicts.
+func mergeConflicts(existing, content []byte, label string) ([]byte, bool) {
+	eol := []byte("\n")
+	if bytes.Contains(existing, []byte("\r\n")) {
+		eol = []byte("\r\n")
+	}
+
+	ours := splitLines(existing)
+	theirs := splitLines(content)
+
+	// Find the longest common subsequence of lines.
+	lcs := make([][]int, len(ours)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(theirs)+1)
+	}
+	for i := len(ours) - 1; i >= 0; i-- {
+		for j := len(theirs) - 1; j >= 0; j-- {
+			if equalLines(ours[i], theirs[j]) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var merged []byte
+	var conflicts bool
+	appendLines := func(lines [][]byte) {
+		for _, line := range lines {
+			merged = append(merged, line...)
+			if !bytes.HasSuffix(line, []byte("\n")) {
+				merged = append(merged, eol...)
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(ours) || j < len(theirs) {
+		if i < len(ours) && j < len(theirs) && equalLines(ours[i], theirs[j]) {
+			merged = append(merged, ours[i]...)
+			i, j = i+1, j+1
+
+			// The last line may not end with a newline, so separate it from any following markers or lines.
+			if !bytes.HasSuffix(merged, []byte("\n")) && (i < len(ours) || j < len(theirs)) {
+				merged = append(merged, eol...)
+			}
+			continue
+		}
+
+		// Collect differing lines until the next common line.
+		oi, tj := i, j
+		for (i < len(ours) || j < len(theirs)) && !(i < len(ours) && j < len(theirs) && equalLines(ours[i], theirs[j])) {
+			if i < len(ours) && (j == len(theirs) || lcs[i+1][j] >= lcs[i][j+1]) {
+				i++
+			} else {
+				j++
+			}
+		}
+
+		conflicts = true
+		merged = append(merged, "<<<<<<< current"...)
+		merged = append(merged, eol...)
+		appendLines(ours[oi:i])
+		merged = append(merged, "======="...)
+		merged = append(merged, eol...)
+		appendLines(theirs[tj:j])
+		merged = append(merged, ">>>>>>> "+label...)
+		merged = append(merged, eol...)
+	}
+
+	return merged, conflicts
+}
+
+// splitLines splits content after each newline, retaining line endings.
+func splitLines(content []byte) [][]byte {
+	lines := bytes.SplitAfter(content, []byte("\n"))
+	if len(lines) > 0 && len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func equalLines(a, b []byte) bool {
+	return bytes.Equal(bytes.TrimRight(a, "\r\n"), bytes.TrimRight(b, "\r\n"))
+}
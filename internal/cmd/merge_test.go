@@ -0,0 +1,259 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/heaths/go-console"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStrategies(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		values   []string
+		declared map[string]string
+		want     map[string]string
+		wantErr  bool
+	}{
+		{
+			name: "default",
+			want: map[string]string{
+				"README.md": strategySkip,
+			},
+		},
+		{
+			name:   "fallback",
+			values: []string{"overwrite"},
+			want: map[string]string{
+				"README.md": strategyOverwrite,
+			},
+		},
+		{
+			name:   "patterns",
+			values: []string{".gitignore=merge", "docs/*=overwrite"},
+			want: map[string]string{
+				".gitignore":      strategyMerge,
+				"src/.gitignore":  strategyMerge,
+				"docs/README.md":  strategyOverwrite,
+				"docs/a/index.md": strategySkip,
+				"README.md":       strategySkip,
+			},
+		},
+		{
+			name:   "flags override manifest",
+			values: []string{"CODEOWNERS=overwrite"},
+			declared: map[string]string{
+				"CODEOWNERS": strategyMerge,
+				"*.md":       strategyPrompt,
+			},
+			want: map[string]string{
+				".github/CODEOWNERS": strategyOverwrite,
+				"README.md":          strategyPrompt,
+			},
+		},
+		{
+			name:    "unsupported",
+			values:  []string{"replace"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid pattern",
+			values:  []string{"[=merge"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := parseStrategies(tt.values, tt.declared)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			for name, want := range tt.want {
+				assert.Equal(t, want, s.strategy(name), name)
+			}
+		})
+	}
+}
+
+func TestMergeTree(t *testing.T) {
+	t.Parallel()
+
+	write := func(t *testing.T, root string, files map[string]string) {
+		for name, content := range files {
+			path := filepath.Join(root, name)
+			require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+			require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		}
+	}
+
+	src := t.TempDir()
+	write(t, src, map[string]string{
+		".gitignore":         "bin/\n*.log\n",
+		".github/CODEOWNERS": "* @heaths\n",
+		"LICENSE":            "MIT\n",
+		"README.md":          "# Template\n",
+		"go.mod":             "module example\n",
+		"Makefile":           "build:\n\tgo build\n\ntest:\n\tgo test\n",
+	})
+
+	dst := t.TempDir()
+	write(t, dst, map[string]string{
+		".gitignore": "*.log\nobj/",
+		"LICENSE":    "Apache-2.0\n",
+		"README.md":  "# Legacy\n",
+		"go.mod":     "module legacy\n",
+		"Makefile":   "build:\n\tmake all\n\ntest:\n\tgo test\n",
+	})
+
+	strategies, err := parseStrategies([]string{"skip", ".gitignore=merge", "Makefile=merge", "LICENSE=overwrite", "README.md=prompt"}, nil)
+	require.NoError(t, err)
+
+	fake := console.Fake(
+		console.WithStdin(bytes.NewBufferString("x\no\n")),
+		console.WithStdinTTY(true),
+		console.WithStderrTTY(true),
+	)
+	opts := &applyOptions{
+		GlobalOptions: &GlobalOptions{
+			Console: fake,
+		},
+	}
+
+	err = mergeTree(src, dst, "", strategies, opts)
+	require.NoError(t, err)
+
+	want := map[string]string{
+		".gitignore":         "*.log\nobj/\nbin/\n",
+		".github/CODEOWNERS": "* @heaths\n",
+		"LICENSE":            "MIT\n",
+		"README.md":          "# Template\n",
+		"go.mod":             "module legacy\n",
+		"Makefile":           "build:\n<<<<<<< current\n\tmake all\n=======\n\tgo build\n>>>>>>> template\n\ntest:\n\tgo test\n",
+	}
+	for name, content := range want {
+		got, err := os.ReadFile(filepath.Join(dst, name))
+		require.NoError(t, err)
+		assert.Equal(t, content, string(got), name)
+	}
+
+	_, stderr, _ := fake.Buffers()
+	assert.Contains(t, stderr.String(), "README.md already exists")
+	assert.Contains(t, stderr.String(), "Please try again")
+	assert.Contains(t, stderr.String(), "Merge conflicts in Makefile")
+}
+
+func TestMergeTree_cannotPrompt(t *testing.T) {
+	t.Parallel()
+
+	src := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(src, "README.md"), []byte("a"), 0o644))
+
+	dst := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dst, "README.md"), []byte("b"), 0o644))
+
+	strategies, err := parseStrategies([]string{"prompt"}, nil)
+	require.NoError(t, err)
+
+	opts := &applyOptions{
+		GlobalOptions: &GlobalOptions{
+			Console: console.Fake(),
+		},
+	}
+
+	err = mergeTree(src, dst, "", strategies, opts)
+	assert.Error(t, err)
+}
+
+func TestMergeLines(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "a\nb\nc\n", string(mergeLines([]byte("a\nb\n"), []byte("b\nc\n"))))
+	assert.Equal(t, "a\r\nb\r\nc\r\n", string(mergeLines([]byte("a\r\nb\r\n"), []byte("c\n"))))
+	assert.Equal(t, "a\nb\n", string(mergeLines([]byte("a"), []byte("b"))))
+	assert.Equal(t, "b\n", string(mergeLines(nil, []byte("b"))))
+}
+
+func TestIsIgnoreFile(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, isIgnoreFile(".gitignore"))
+	assert.True(t, isIgnoreFile("src/.dockerignore"))
+	assert.True(t, isIgnoreFile(".git/info/exclude"))
+	assert.False(t, isIgnoreFile("CODEOWNERS"))
+	assert.False(t, isIgnoreFile("ignore"))
+	assert.False(t, isIgnoreFile("go.mod"))
+}
+
+func TestMergeConflicts(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		existing      string
+		content       string
+		want          string
+		wantConflicts bool
+	}{
+		{
+			name:     "same",
+			existing: "a\n\nb\n",
+			content:  "a\n\nb\n",
+			want:     "a\n\nb\n",
+		},
+		{
+			name:          "changed",
+			existing:      "a\nb\nc\n",
+			content:       "a\nx\nc\n",
+			want:          "a\n<<<<<<< current\nb\n=======\nx\n>>>>>>> owner/repo\nc\n",
+			wantConflicts: true,
+		},
+		{
+			name:          "added",
+			existing:      "a\n",
+			content:       "a\n\na\n",
+			want:          "a\n<<<<<<< current\n=======\n\na\n>>>>>>> owner/repo\n",
+			wantConflicts: true,
+		},
+		{
+			name:          "common line without final newline",
+			existing:      "a",
+			content:       "a\nb\n",
+			want:          "a\n<<<<<<< current\n=======\nb\n>>>>>>> owner/repo\n",
+			wantConflicts: true,
+		},
+		{
+			name:     "same without final newline",
+			existing: "a\nb",
+			content:  "a\nb",
+			want:     "a\nb",
+		},
+		{
+			name:          "crlf without final newline",
+			existing:      "a\r\nb",
+			content:       "a\nc",
+			want:          "a\r\n<<<<<<< current\r\nb\r\n=======\r\nc\r\n>>>>>>> owner/repo\r\n",
+			wantConflicts: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, conflicts := mergeConflicts([]byte(tt.existing), []byte(tt.content), "owner/repo")
+			assert.Equal(t, tt.want, string(got))
+			assert.Equal(t, tt.wantConflicts, conflicts)
+		})
+	}
+}
@@ -4,10 +4,15 @@
 package cmd
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 
 	"github.com/cli/go-gh"
+	"github.com/cli/go-gh/pkg/api"
 	"github.com/cli/go-gh/pkg/auth"
 	"github.com/cli/go-gh/pkg/repository"
 	"github.com/heaths/go-console"
@@ -20,6 +25,9 @@ type GlobalOptions struct {
 
 	Repo repository.Repository
 
+	// Context is cancelled when the command is interrupted.
+	Context context.Context
+
 	// Test-only options.
 	host      string
 	authToken string
@@ -54,3 +62,98 @@ func (opts *GlobalOptions) IsAuthenticated() error {
 
 	return nil
 }
+
+func (opts *GlobalOptions) restClient(host string) (api.RESTClient, error) {
+	clientOpts := &api.ClientOptions{
+		AuthToken: opts.authToken,
+		Host:      host,
+	}
+	if opts.host != "" {
+		clientOpts.Host = opts.host
+	}
+
+	return gh.RESTClient(clientOpts)
+}
+
+func (opts *GlobalOptions) gqlClient(host string) (api.GQLClient, error) {
+	clientOpts := &api.ClientOptions{
+		AuthToken: opts.authToken,
+		Host:      host,
+	}
+	if opts.host != "" {
+		clientOpts.Host = opts.host
+	}
+
+	return gh.GQLClient(clientOpts)
+}
+
+// send marshals body as JSON and sends it to path, decoding the response if not nil.
+func send(client api.RESTClient, method, path string, body, response any) error {
+	content, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	return client.Do(method, path, bytes.NewReader(content), response)
+}
+
+func (opts *GlobalOptions) logVerbose(format string, v ...any) {
+	if opts.Verbose && opts.Log != nil {
+		opts.Log.Printf(format, v...)
+	}
+}
+
+// isInteractive returns true if the user can be prompted, which requires both stdin and stderr to be terminals.
+func (opts *GlobalOptions) isInteractive() bool {
+	return opts.Console.IsStdinTTY() && opts.Console.IsStderrTTY()
+}
+
+// logWarning writes a warning to stderr regardless of whether verbose logging is enabled.
+func (opts *GlobalOptions) logWarning(format string, v ...any) {
+	if opts.Console != nil {
+		fmt.Fprintf(opts.Console.Stderr(), "warning: "+format+"\n", v...)
+	}
+}
+
+// context returns the Context, or a background context if not set.
+func (opts *GlobalOptions) context() context.Context {
+	if opts.Context == nil {
+		return context.Background()
+	}
+	return opts.Context
+}
+
+// stdin returns the console's stdin, which stops reading once the Context is cancelled so that prompts do not block.
+func (opts *GlobalOptions) stdin() io.Reader {
+	return &contextReader{ctx: opts.context(), r: opts.Console.Stdin()}
+}
+
+// contextReader returns errInterrupted from Read once ctx is done, even if the underlying Read is still blocked.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if r.ctx.Err() != nil {
+		return 0, errInterrupted
+	}
+
+	type result struct {
+		n   int
+		err error
+	}
+	buf := make([]byte, len(p))
+	read := make(chan result, 1)
+	go func() {
+		n, err := r.r.Read(buf)
+		read <- result{n, err}
+	}()
+
+	select {
+	case res := <-read:
+		return copy(p, buf[:res.n]), res.err
+	case <-r.ctx.Done():
+		return 0, errInterrupted
+	}
+}
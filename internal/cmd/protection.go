@@ -0,0 +1,119 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/cli/go-gh/pkg/api"
+)
+
+// protectionFields are required by the branch protection API even if null.
+var protectionFields = []string{
+	"required_status_checks",
+	"enforce_admins",
+	"required_pull_request_reviews",
+	"restrictions",
+}
+
+// protectRepository applies branch protection rules and rulesets declared by the template manifest
+// to the current repository. Payloads are sent as declared after rendering any string values as templates.
+func protectRepository(opts *applyOptions) error {
+	if opts.manifest == nil || len(opts.manifest.Protection) == 0 && len(opts.manifest.Rulesets) == 0 {
+		return nil
+	}
+
+	client, err := opts.restClient(opts.Repo.Host())
+	if err != nil {
+		return err
+	}
+
+	path := fmt.Sprintf("repos/%s/%s", opts.Repo.Owner(), opts.Repo.Name())
+
+	for _, branch := range sortedKeys(opts.manifest.Protection) {
+		rules := opts.manifest.Protection[branch]
+		if branch, err = expand(branch, opts); err != nil {
+			return fmt.Errorf("failed to expand branch protection: %w", err)
+		}
+
+		payload, err := expandValue(rules, opts)
+		if err != nil {
+			return fmt.Errorf("failed to expand branch protection for %s: %w", branch, err)
+		}
+
+		body, ok := payload.(map[string]any)
+		if !ok {
+			body = make(map[string]any)
+		}
+		for _, field := range protectionFields {
+			if _, ok := body[field]; !ok {
+				body[field] = nil
+			}
+		}
+
+		opts.logVerbose("protecting branch %s", branch)
+		if err = send(client, http.MethodPut, fmt.Sprintf("%s/branches/%s/protection", path, url.PathEscape(branch)), body, nil); err != nil {
+			return protectionError(fmt.Sprintf("protect branch %s", branch), opts, err)
+		}
+	}
+
+	for _, ruleset := range opts.manifest.Rulesets {
+		body, err := expandValue(ruleset, opts)
+		if err != nil {
+			return fmt.Errorf("failed to expand ruleset: %w", err)
+		}
+
+		name := ruleset["name"]
+		opts.logVerbose("creating ruleset %v", name)
+		if err = send(client, http.MethodPost, path+"/rulesets", body, nil); err != nil {
+			return protectionError(fmt.Sprintf("create ruleset %v", name), opts, err)
+		}
+	}
+
+	return nil
+}
+
+// protectionError returns a clear error when the user is not an administrator, which GitHub reports as not found or forbidden.
+func protectionError(action string, opts *applyOptions, err error) error {
+	var httpErr api.HTTPError
+	if errors.As(err, &httpErr) && (httpErr.StatusCode == http.StatusForbidden || httpErr.StatusCode == http.StatusNotFound) {
+		return fmt.Errorf(
+			"failed to %s: you must be an administrator of %s/%s, or pass --skip-protection to skip: %w",
+			action, opts.Repo.Owner(), opts.Repo.Name(), err,
+		)
+	}
+
+	return fmt.Errorf("failed to %s: %w", action, err)
+}
+
+// expandValue renders all strings within maps and slices decoded from YAML as templates.
+func expandValue(v any, opts *applyOptions) (_ any, err error) {
+	switch v := v.(type) {
+	case string:
+		return expand(v, opts)
+
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for key, value := range v {
+			if m[key], err = expandValue(value, opts); err != nil {
+				return
+			}
+		}
+		return m, nil
+
+	case []any:
+		s := make([]any, len(v))
+		for i, value := range v {
+			if s[i], err = expandValue(value, opts); err != nil {
+				return
+			}
+		}
+		return s, nil
+	}
+
+	return v, nil
+}
@@ -0,0 +1,120 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/cli/go-gh/pkg/repository"
+	"github.com/heaths/go-console"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/h2non/gock.v1"
+	"gopkg.in/yaml.v3"
+)
+
+func TestProtectRepository(t *testing.T) {
+	const content = `
+protection:
+  '{{param "github.defaultBranch"}}':
+    required_pull_request_reviews:
+      required_approving_review_count: 1
+    enforce_admins: true
+rulesets:
+- name: '{{param "github.repo"}} releases'
+  target: tag
+  enforcement: active
+  conditions:
+    ref_name:
+      include: ['refs/tags/v*']
+      exclude: []
+  rules:
+  - type: deletion
+`
+
+	tests := []struct {
+		name    string
+		mocks   func()
+		wantErr string
+	}{
+		{
+			name: "protected",
+			mocks: func() {
+				gock.New("https://api.github.com").
+					Put("/repos/heaths/example/branches/main/protection").
+					BodyString(`{
+						"required_status_checks": null,
+						"enforce_admins": true,
+						"required_pull_request_reviews": {
+							"required_approving_review_count": 1
+						},
+						"restrictions": null
+					}`).
+					Reply(200).
+					JSON(`{}`)
+				gock.New("https://api.github.com").
+					Post("/repos/heaths/example/rulesets").
+					BodyString(`{
+						"name": "example releases",
+						"target": "tag",
+						"enforcement": "active",
+						"conditions": {
+							"ref_name": {
+								"include": ["refs/tags/v*"],
+								"exclude": []
+							}
+						},
+						"rules": [{"type": "deletion"}]
+					}`).
+					Reply(201).
+					JSON(`{}`)
+			},
+		},
+		{
+			name: "not admin",
+			mocks: func() {
+				gock.New("https://api.github.com").
+					Put("/repos/heaths/example/branches/main/protection").
+					Reply(404).
+					JSON(`{"message": "Not Found"}`)
+			},
+			wantErr: "failed to protect branch main: you must be an administrator of heaths/example, or pass --skip-protection to skip: HTTP 404: Not Found (https://api.github.com/repos/heaths/example/branches/main/protection)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(gock.Off)
+			tt.mocks()
+
+			var m manifest
+			require.NoError(t, yaml.Unmarshal([]byte(content), &m))
+
+			repo, err := repository.Parse("heaths/example")
+			require.NoError(t, err)
+
+			opts := &applyOptions{
+				GlobalOptions: &GlobalOptions{
+					Console:   console.Fake(),
+					Repo:      repo,
+					authToken: "***",
+					host:      "github.com",
+				},
+				manifest: &m,
+				params: map[string]string{
+					"github.defaultBranch": "main",
+					"github.repo":          "example",
+				},
+			}
+
+			err = protectRepository(opts)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.True(t, gock.IsDone(), pendingMocks(gock.Pending()))
+		})
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// provider defines built-in variables that are only resolved when a template first references them.
+type provider struct {
+	names   []string
+	resolve func(opts *applyOptions) (map[string]string, error)
+}
+
+func providers(opts *applyOptions) []provider {
+	env := make([]string, len(opts.environment))
+	for i, name := range opts.environment {
+		env[i] = "env." + name
+	}
+
+	return []provider{
+		{
+			names:   []string{"github.login", "github.name"},
+			resolve: resolveUser,
+		},
+		{
+			names:   []string{"github.ownerName"},
+			resolve: resolveOwner,
+		},
+		{
+			names:   []string{"system.os", "system.arch"},
+			resolve: resolveSystem,
+		},
+		{
+			names:   env,
+			resolve: resolveEnvironment,
+		},
+	}
+}
+
+// sensitiveParam returns true if name is resolved from a provider whose values may contain secrets
+// e.g., environment variables, which are never saved to disk.
+func sensitiveParam(name string) bool {
+	return strings.HasPrefix(name, "env.")
+}
+
+// providerResolver returns a function that resolves built-in variables from providers the first time a template
+// references them. Each provider is resolved at most once, and providers that fail are logged and not retried.
+func providerResolver(opts *applyOptions) func(name string) (string, bool) {
+	registered := providers(opts)
+	resolved := make([]map[string]string, len(registered))
+
+	return func(name string) (string, bool) {
+		for i, p := range registered {
+			if !slices.Contains(p.names, name) {
+				continue
+			}
+
+			if resolved[i] == nil {
+				values, err := p.resolve(opts)
+				if err != nil {
+					opts.logVerbose("failed to resolve %s: %v", strings.Join(p.names, ", "), err)
+					values = make(map[string]string)
+				}
+				resolved[i] = values
+			}
+
+			value, ok := resolved[i][name]
+			return value, ok
+		}
+
+		return "", false
+	}
+}
+
+func resolveUser(opts *applyOptions) (map[string]string, error) {
+	if opts.Repo == nil {
+		return nil, fmt.Errorf("no repository")
+	}
+
+	client, err := opts.restClient(opts.Repo.Host())
+	if err != nil {
+		return nil, err
+	}
+
+	var user struct {
+		Login string
+		Name  string
+	}
+	if err = client.Get("user", &user); err != nil {
+		return nil, err
+	}
+
+	return map[string]string{
+		"github.login": user.Login,
+		"github.name":  user.Name,
+	}, nil
+}
+
+func resolveOwner(opts *applyOptions) (map[string]string, error) {
+	if opts.Repo == nil {
+		return nil, fmt.Errorf("no repository")
+	}
+
+	client, err := opts.restClient(opts.Repo.Host())
+	if err != nil {
+		return nil, err
+	}
+
+	var owner struct {
+		Login string
+		Name  string
+	}
+	if err = client.Get("users/"+opts.Repo.Owner(), &owner); err != nil {
+		return nil, err
+	}
+
+	name := owner.Name
+	if name == "" {
+		name = owner.Login
+	}
+
+	return map[string]string{
+		"github.ownerName": name,
+	}, nil
+}
+
+func resolveSystem(opts *applyOptions) (map[string]string, error) {
+	return map[string]string{
+		"system.os":   runtime.GOOS,
+		"system.arch": runtime.GOARCH,
+	}, nil
+}
+
+func resolveEnvironment(opts *applyOptions) (map[string]string, error) {
+	values := make(map[string]string, len(opts.environment))
+	for _, name := range opts.environment {
+		if value, ok := os.LookupEnv(name); ok {
+			values["env."+name] = value
+		}
+	}
+
+	return values, nil
+}
@@ -0,0 +1,171 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/cli/go-gh/pkg/repository"
+	"github.com/heaths/go-console"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestProviderResolver(t *testing.T) {
+	t.Setenv("GH_TEMPLATE_TEST", "value")
+
+	tests := []struct {
+		name  string
+		names []string
+		mocks func()
+		want  map[string]string
+	}{
+		{
+			name:  "unknown",
+			names: []string{"github.repo", "env.PATH"},
+			want:  map[string]string{},
+		},
+		{
+			name:  "user resolved once",
+			names: []string{"github.login", "github.name", "github.login"},
+			mocks: func() {
+				gock.New("https://api.github.com").
+					Get("/user").
+					Times(1).
+					Reply(200).
+					JSON(`{
+						"login": "heaths",
+						"name": "Heath Stewart"
+					}`)
+			},
+			want: map[string]string{
+				"github.login": "heaths",
+				"github.name":  "Heath Stewart",
+			},
+		},
+		{
+			name:  "owner without name",
+			names: []string{"github.ownerName"},
+			mocks: func() {
+				gock.New("https://api.github.com").
+					Get("/users/heaths").
+					Reply(200).
+					JSON(`{
+						"login": "heaths",
+						"name": null
+					}`)
+			},
+			want: map[string]string{
+				"github.ownerName": "heaths",
+			},
+		},
+		{
+			name:  "owner failed",
+			names: []string{"github.ownerName", "github.ownerName"},
+			mocks: func() {
+				gock.New("https://api.github.com").
+					Get("/users/heaths").
+					Times(1).
+					Reply(404).
+					JSON(`{"message": "Not Found"}`)
+			},
+			want: map[string]string{},
+		},
+		{
+			name:  "system and environment",
+			names: []string{"system.os", "env.GH_TEMPLATE_TEST", "env.UNDEFINED_GH_TEMPLATE_TEST"},
+			want: map[string]string{
+				"system.os":            runtime.GOOS,
+				"env.GH_TEMPLATE_TEST": "value",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(gock.Off)
+			if tt.mocks != nil {
+				tt.mocks()
+			}
+
+			repo, err := repository.Parse("github.com/heaths/gh-template")
+			require.NoError(t, err)
+
+			opts := &applyOptions{
+				GlobalOptions: &GlobalOptions{
+					Repo: repo,
+
+					authToken: "***",
+					host:      "github.com",
+				},
+				environment: []string{"GH_TEMPLATE_TEST", "UNDEFINED_GH_TEMPLATE_TEST"},
+			}
+
+			resolve := providerResolver(opts)
+			got := make(map[string]string)
+			for _, name := range tt.names {
+				if value, ok := resolve(name); ok {
+					got[name] = value
+				}
+			}
+
+			assert.True(t, gock.IsDone(), pendingMocks(gock.Pending()))
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestApplyDir_providers(t *testing.T) {
+	t.Cleanup(gock.Off)
+	gock.New("https://api.github.com").
+		Get("/user").
+		Times(1).
+		Reply(200).
+		JSON(`{"login": "heaths", "name": "Heath Stewart"}`)
+
+	root := t.TempDir()
+	files := map[string]string{
+		"README.md":  `{{param "github.login"}} ({{param "github.name"}})`,
+		"docs/a.md":  `{{param "github.login"}}`,
+		"image.png":  `"github.ownerName"`,
+		"notes.txt":  `"github.ownerName" is mentioned but not referenced`,
+		"config.yml": `os: {{param "system.os"}}`,
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+
+	repo, err := repository.Parse("github.com/heaths/gh-template")
+	require.NoError(t, err)
+
+	opts := &applyOptions{
+		GlobalOptions: &GlobalOptions{
+			Console: console.Fake(),
+			Repo:    repo,
+
+			authToken: "***",
+			host:      "github.com",
+		},
+		params: map[string]string{},
+	}
+
+	require.NoError(t, applyDir(root, opts))
+	assert.True(t, gock.IsDone(), pendingMocks(gock.Pending()))
+
+	content, err := os.ReadFile(filepath.Join(root, "README.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "heaths (Heath Stewart)", string(content))
+
+	content, err = os.ReadFile(filepath.Join(root, "config.yml"))
+	require.NoError(t, err)
+	assert.Equal(t, "os: "+runtime.GOOS, string(content))
+
+	assert.NotContains(t, opts.params, "github.ownerName")
+}
@@ -0,0 +1,144 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/heaths/gh-template/internal/git"
+	"gopkg.in/yaml.v3"
+)
+
+// cloneStateFile is the path relative to the .git directory where progress of a clone is saved.
+const cloneStateFile = "gh-template/clone.yml"
+
+// Steps of a clone recorded as they complete so that a failed clone can be resumed.
+const (
+	stepCreate     = "create"
+	stepApply      = "apply"
+	stepSettings   = "settings"
+	stepAccess     = "access"
+	stepLabels     = "labels"
+	stepSeed       = "seed"
+	stepActions    = "actions"
+	stepCommit     = "commit"
+	stepPush       = "push"
+	stepProtection = "protection"
+)
+
+// cloneState records completed steps of a clone along with parameters and the template manifest,
+// which is deleted after templates are applied.
+type cloneState struct {
+	path string
+
+	Template   string            `yaml:"template"`
+	Repository string            `yaml:"repository"`
+	Steps      []string          `yaml:"steps"`
+	Params     map[string]string `yaml:"params,omitempty"`
+	Manifest   *manifest         `yaml:"manifest,omitempty"`
+	Labels     []label           `yaml:"labels,omitempty"`
+}
+
+// cloneStatePath gets the path to the clone state file for the repository containing root.
+func cloneStatePath(root string) (string, error) {
+	dir, err := git.Dir(root)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, cloneStateFile), nil
+}
+
+// newCloneState returns empty state saved to the repository containing root.
+func newCloneState(root, template string) (*cloneState, error) {
+	path, err := cloneStatePath(root)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cloneState{
+		path:     path,
+		Template: template,
+	}, nil
+}
+
+// readCloneState reads state saved to the repository containing root by a previous clone,
+// or returns nil if root is not a repository or no state was saved.
+func readCloneState(root string) (*cloneState, error) {
+	path, err := cloneStatePath(root)
+	if err != nil {
+		if errors.Is(err, git.ErrNotRepository) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	state := &cloneState{path: path}
+	if err = yaml.Unmarshal(content, state); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if state.Manifest != nil {
+		state.Manifest.labels = state.Labels
+	}
+
+	return state, nil
+}
+
+// done returns true if step was completed.
+func (s *cloneState) done(step string) bool {
+	for _, completed := range s.Steps {
+		if completed == step {
+			return true
+		}
+	}
+	return false
+}
+
+// complete records step as completed and saves the state.
+// Sensitive parameters are not saved and are resolved again when resuming.
+func (s *cloneState) complete(step string) error {
+	s.Steps = append(s.Steps, step)
+	if s.Manifest != nil {
+		s.Labels = s.Manifest.labels
+	}
+
+	saved := *s
+	saved.Params = make(map[string]string, len(s.Params))
+	for name, value := range s.Params {
+		if !sensitiveParam(name) {
+			saved.Params[name] = value
+		}
+	}
+
+	content, err := yaml.Marshal(&saved)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(s.path, content, 0o600)
+}
+
+// remove deletes saved state after a clone completes.
+func (s *cloneState) remove() error {
+	if err := os.Remove(s.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
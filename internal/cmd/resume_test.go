@@ -0,0 +1,80 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package cmd
+
+import (
+	"os"
+	"testing"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloneState(t *testing.T) {
+	root := t.TempDir()
+	_, err := gogit.PlainInit(root, false)
+	require.NoError(t, err)
+
+	state, err := readCloneState(root)
+	require.NoError(t, err)
+	assert.Nil(t, state)
+
+	state, err = newCloneState(root, "heaths/template-golang")
+	require.NoError(t, err)
+	require.NoError(t, state.complete(stepCreate))
+
+	enabled := true
+	state.Params = map[string]string{"name": "example", "env.TOKEN": "secret"}
+	state.Manifest = &manifest{
+		Partials: "_templates",
+		Repository: &repositorySettings{
+			DefaultBranch:  "main",
+			Topics:         []string{"go"},
+			HasDiscussions: &enabled,
+		},
+		Protection: map[string]map[string]any{
+			"main": {"enforce_admins": true},
+		},
+		Labels: ".github/labels.yml",
+		labels: []label{{Name: "bug", Color: "d73a4a"}},
+	}
+	require.NoError(t, state.complete(stepApply))
+
+	got, err := readCloneState(root)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+
+	assert.Equal(t, "heaths/template-golang", got.Template)
+	assert.True(t, got.done(stepCreate))
+	assert.True(t, got.done(stepApply))
+	assert.False(t, got.done(stepSettings))
+	// Sensitive parameters are never saved, but are still available to the current clone.
+	assert.Equal(t, map[string]string{"name": "example"}, got.Params)
+	assert.Equal(t, "secret", state.Params["env.TOKEN"])
+
+	content, err := os.ReadFile(state.path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "secret")
+	if assert.NotNil(t, got.Manifest) {
+		assert.Equal(t, "_templates", got.Manifest.Partials)
+		assert.Equal(t, state.Manifest.Repository, got.Manifest.Repository)
+		assert.Equal(t, state.Manifest.Protection, got.Manifest.Protection)
+		assert.Equal(t, []label{{Name: "bug", Color: "d73a4a"}}, got.Manifest.labels)
+	}
+
+	require.NoError(t, got.remove())
+	_, err = os.Stat(got.path)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	got, err = readCloneState(root)
+	require.NoError(t, err)
+	assert.Nil(t, got)
+}
+
+func TestReadCloneState_notRepository(t *testing.T) {
+	state, err := readCloneState(t.TempDir())
+	require.NoError(t, err)
+	assert.Nil(t, state)
+}
@@ -0,0 +1,259 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type milestone struct {
+	Title       string `yaml:"title"`
+	Description string `yaml:"description"`
+
+	// DueOn is an ISO 8601 timestamp e.g., "2023-01-31T00:00:00Z".
+	DueOn string `yaml:"dueOn"`
+}
+
+type issue struct {
+	Title     string   `yaml:"title"`
+	Body      string   `yaml:"body"`
+	Labels    []string `yaml:"labels"`
+	Assignees []string `yaml:"assignees"`
+
+	// Milestone is the title of a milestone declared by the template.
+	Milestone string `yaml:"milestone"`
+}
+
+type project struct {
+	Title string `yaml:"title"`
+}
+
+const queryRepositoryIDs = `query RepositoryIDs($owner: String!, $name: String!) {
+	repository(owner: $owner, name: $name) {
+		id
+		owner {
+			id
+		}
+		projectsV2(first: 100) {
+			nodes {
+				title
+			}
+		}
+	}
+}`
+
+const mutationCreateProject = `mutation CreateProject($ownerId: ID!, $repositoryId: ID!, $title: String!) {
+	createProjectV2(input: {ownerId: $ownerId, repositoryId: $repositoryId, title: $title}) {
+		projectV2 {
+			number
+		}
+	}
+}`
+
+// seedRepository creates milestones, issues, and projects declared by the template manifest.
+// Labels should already exist since issues may reference them. Any with the same title as one
+// that already exists e.g., created by a previous clone that was resumed, are not created again.
+func seedRepository(opts *applyOptions) error {
+	if opts.manifest == nil || len(opts.manifest.Milestones) == 0 && len(opts.manifest.Issues) == 0 && len(opts.manifest.Projects) == 0 {
+		return nil
+	}
+
+	// Validate milestones referenced by issues before creating anything.
+	declared := make(map[string]bool, len(opts.manifest.Milestones))
+	for _, m := range opts.manifest.Milestones {
+		declared[m.Title] = true
+	}
+	for _, i := range opts.manifest.Issues {
+		if i.Milestone != "" && !declared[i.Milestone] {
+			title, err := expand(i.Title, opts)
+			if err != nil {
+				return fmt.Errorf("failed to expand issue %q: %w", i.Title, err)
+			}
+			return fmt.Errorf("issue %q references undeclared milestone %q", title, i.Milestone)
+		}
+	}
+
+	client, err := opts.restClient(opts.Repo.Host())
+	if err != nil {
+		return err
+	}
+
+	path := fmt.Sprintf("repos/%s/%s", opts.Repo.Owner(), opts.Repo.Name())
+
+	milestones := make(map[string]int, len(opts.manifest.Milestones))
+	existingMilestones := make(map[string]int)
+	if len(opts.manifest.Milestones) > 0 {
+		var existing []struct {
+			Number int
+			Title  string
+		}
+		if err = client.Get(path+"/milestones?state=all&per_page=100", &existing); err != nil {
+			return fmt.Errorf("failed to get milestones: %w", err)
+		}
+		for _, m := range existing {
+			existingMilestones[m.Title] = m.Number
+		}
+	}
+
+	for _, m := range opts.manifest.Milestones {
+		body := make(map[string]string, 3)
+		for key, value := range map[string]string{
+			"title":       m.Title,
+			"description": m.Description,
+			"due_on":      m.DueOn,
+		} {
+			if body[key], err = expand(value, opts); err != nil {
+				return fmt.Errorf("failed to expand milestone %q: %w", m.Title, err)
+			}
+			if body[key] == "" {
+				delete(body, key)
+			}
+		}
+
+		if number, ok := existingMilestones[body["title"]]; ok {
+			opts.logVerbose("skipping existing milestone %q", body["title"])
+			milestones[m.Title] = number
+			continue
+		}
+
+		var created struct {
+			Number int `json:"number"`
+		}
+
+		opts.logVerbose("creating milestone %q", body["title"])
+		if err = send(client, http.MethodPost, path+"/milestones", body, &created); err != nil {
+			return fmt.Errorf("failed to create milestone %q: %w", body["title"], err)
+		}
+		milestones[m.Title] = created.Number
+	}
+
+	existingIssues := make(map[string]bool)
+	if len(opts.manifest.Issues) > 0 {
+		var existing []struct {
+			Title string
+		}
+		if err = client.Get(path+"/issues?state=all&per_page=100", &existing); err != nil {
+			return fmt.Errorf("failed to get issues: %w", err)
+		}
+		for _, i := range existing {
+			existingIssues[i.Title] = true
+		}
+	}
+
+	for _, i := range opts.manifest.Issues {
+		title, err := expand(i.Title, opts)
+		if err != nil {
+			return fmt.Errorf("failed to expand issue %q: %w", i.Title, err)
+		}
+
+		body := map[string]any{"title": title}
+		if body["body"], err = expand(i.Body, opts); err != nil {
+			return fmt.Errorf("failed to expand issue %q: %w", title, err)
+		}
+		if body["labels"], err = expandStrings(i.Labels, opts); err != nil {
+			return fmt.Errorf("failed to expand issue %q: %w", title, err)
+		}
+		if body["assignees"], err = expandStrings(i.Assignees, opts); err != nil {
+			return fmt.Errorf("failed to expand issue %q: %w", title, err)
+		}
+
+		if i.Milestone != "" {
+			body["milestone"] = milestones[i.Milestone]
+		}
+
+		if existingIssues[title] {
+			opts.logVerbose("skipping existing issue %q", title)
+			continue
+		}
+
+		opts.logVerbose("creating issue %q", title)
+		if err = send(client, http.MethodPost, path+"/issues", body, nil); err != nil {
+			return fmt.Errorf("failed to create issue %q: %w", title, err)
+		}
+	}
+
+	if len(opts.manifest.Projects) == 0 {
+		return nil
+	}
+
+	gql, err := opts.gqlClient(opts.Repo.Host())
+	if err != nil {
+		return err
+	}
+
+	var ids struct {
+		Repository struct {
+			ID    string
+			Owner struct {
+				ID string
+			}
+			ProjectsV2 struct {
+				Nodes []struct {
+					Title string
+				}
+			}
+		}
+	}
+	err = gql.Do(queryRepositoryIDs, map[string]interface{}{
+		"owner": opts.Repo.Owner(),
+		"name":  opts.Repo.Name(),
+	}, &ids)
+	if err != nil {
+		return fmt.Errorf("failed to get repository information: %w", err)
+	}
+
+	existingProjects := make(map[string]bool, len(ids.Repository.ProjectsV2.Nodes))
+	for _, p := range ids.Repository.ProjectsV2.Nodes {
+		existingProjects[p.Title] = true
+	}
+
+	for _, p := range opts.manifest.Projects {
+		title, err := expand(p.Title, opts)
+		if err != nil {
+			return fmt.Errorf("failed to expand project %q: %w", p.Title, err)
+		}
+		if existingProjects[title] {
+			opts.logVerbose("skipping existing project %q", title)
+			continue
+		}
+
+		var created struct {
+			CreateProjectV2 struct {
+				ProjectV2 struct {
+					Number int
+				}
+			}
+		}
+
+		opts.logVerbose("creating project %q", title)
+		err = gql.Do(mutationCreateProject, map[string]interface{}{
+			"ownerId":      ids.Repository.Owner.ID,
+			"repositoryId": ids.Repository.ID,
+			"title":        title,
+		}, &created)
+		if err != nil {
+			return fmt.Errorf("failed to create project %q: %w", title, err)
+		}
+	}
+
+	return nil
+}
+
+// expandStrings renders each string as a template, omitting any that are empty e.g., an unset parameter.
+func expandStrings(values []string, opts *applyOptions) ([]string, error) {
+	expanded := make([]string, 0, len(values))
+	for _, value := range values {
+		value, err := expand(value, opts)
+		if err != nil {
+			return nil, err
+		}
+		if value = strings.TrimSpace(value); value != "" {
+			expanded = append(expanded, value)
+		}
+	}
+
+	return expanded, nil
+}
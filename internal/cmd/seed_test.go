@@ -0,0 +1,205 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/cli/go-gh/pkg/repository"
+	"github.com/heaths/go-console"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/language"
+	"gopkg.in/h2non/gock.v1"
+	"gopkg.in/yaml.v3"
+)
+
+func TestSeedRepository(t *testing.T) {
+	t.Cleanup(gock.Off)
+
+	gock.New("https://api.github.com").
+		Get("/repos/heaths/example/milestones").
+		MatchParam("state", "all").
+		Reply(200).
+		JSON(`[]`)
+	gock.New("https://api.github.com").
+		Get("/repos/heaths/example/issues").
+		MatchParam("state", "all").
+		Reply(200).
+		JSON(`[]`)
+	gock.New("https://api.github.com").
+		Post("/repos/heaths/example/milestones").
+		BodyString(`{"title": "v1.0", "due_on": "2023-01-31T00:00:00Z"}`).
+		Reply(201).
+		JSON(`{"number": 3}`)
+	gock.New("https://api.github.com").
+		Post("/repos/heaths/example/issues").
+		BodyString(`{
+			"title": "Set up Example Service",
+			"body": "Owned by @heaths.\n",
+			"labels": ["onboarding"],
+			"assignees": ["heaths"],
+			"milestone": 3
+		}`).
+		Reply(201).
+		JSON(`{}`)
+	gock.New("https://api.github.com").
+		Post("/repos/heaths/example/issues").
+		BodyString(`{"title": "Write docs", "body": "", "labels": [], "assignees": []}`).
+		Reply(201).
+		JSON(`{}`)
+	gock.New("https://api.github.com").
+		Post("/graphql").
+		AddMatcher(bodyContains(`query RepositoryIDs`, `"variables":{"name":"example","owner":"heaths"}`)).
+		Reply(200).
+		JSON(`{"data": {"repository": {"id": "R_1", "owner": {"id": "U_1"}, "projectsV2": {"nodes": []}}}}`)
+	gock.New("https://api.github.com").
+		Post("/graphql").
+		AddMatcher(bodyContains(`mutation CreateProject`, `"variables":{"ownerId":"U_1","repositoryId":"R_1","title":"Example Service roadmap"}`)).
+		Reply(200).
+		JSON(`{"data": {"createProjectV2": {"projectV2": {"number": 1}}}}`)
+
+	var m manifest
+	require.NoError(t, yaml.Unmarshal([]byte(`
+milestones:
+- title: v1.0
+  dueOn: 2023-01-31T00:00:00Z
+issues:
+- title: 'Set up {{param "name"}}'
+  body: |
+    Owned by @{{param "github.owner"}}.
+  labels:
+  - onboarding
+  assignees:
+  - '{{param "github.owner"}}'
+  milestone: v1.0
+- title: Write docs
+  assignees:
+  - '{{param "git.login"}}'
+projects:
+- title: '{{param "name"}} roadmap'
+`), &m))
+
+	repo, err := repository.Parse("heaths/example")
+	require.NoError(t, err)
+
+	opts := &applyOptions{
+		GlobalOptions: &GlobalOptions{
+			Console:   console.Fake(),
+			Repo:      repo,
+			authToken: "***",
+			host:      "github.com",
+		},
+		language: language.English,
+		manifest: &m,
+		params: map[string]string{
+			"name":         "Example Service",
+			"github.owner": "heaths",
+			"git.login":    "",
+		},
+	}
+
+	err = seedRepository(opts)
+	require.NoError(t, err)
+	assert.True(t, gock.IsDone(), pendingMocks(gock.Pending()))
+}
+
+func TestSeedRepository_resumed(t *testing.T) {
+	t.Cleanup(gock.Off)
+
+	// A previous clone created the milestone, first issue, and project before failing.
+	gock.New("https://api.github.com").
+		Get("/repos/heaths/example/milestones").
+		MatchParam("state", "all").
+		Reply(200).
+		JSON(`[{"number": 3, "title": "v1.0"}]`)
+	gock.New("https://api.github.com").
+		Get("/repos/heaths/example/issues").
+		MatchParam("state", "all").
+		Reply(200).
+		JSON(`[{"number": 1, "title": "Release"}]`)
+	gock.New("https://api.github.com").
+		Post("/repos/heaths/example/issues").
+		BodyString(`{"title": "Write docs", "body": "", "labels": [], "assignees": [], "milestone": 3}`).
+		Reply(201).
+		JSON(`{}`)
+	gock.New("https://api.github.com").
+		Post("/graphql").
+		AddMatcher(bodyContains(`query RepositoryIDs`)).
+		Reply(200).
+		JSON(`{"data": {"repository": {"id": "R_1", "owner": {"id": "U_1"}, "projectsV2": {"nodes": [{"title": "Roadmap"}]}}}}`)
+
+	repo, err := repository.Parse("heaths/example")
+	require.NoError(t, err)
+
+	opts := &applyOptions{
+		GlobalOptions: &GlobalOptions{
+			Console:   console.Fake(),
+			Repo:      repo,
+			authToken: "***",
+			host:      "github.com",
+		},
+		manifest: &manifest{
+			Milestones: []milestone{
+				{Title: "v1.0"},
+			},
+			Issues: []issue{
+				{Title: "Release", Milestone: "v1.0"},
+				{Title: "Write docs", Milestone: "v1.0"},
+			},
+			Projects: []project{
+				{Title: "Roadmap"},
+			},
+		},
+	}
+
+	err = seedRepository(opts)
+	require.NoError(t, err)
+	assert.True(t, gock.IsDone(), pendingMocks(gock.Pending()))
+}
+
+func TestSeedRepository_undeclaredMilestone(t *testing.T) {
+	repo, err := repository.Parse("heaths/example")
+	require.NoError(t, err)
+
+	opts := &applyOptions{
+		GlobalOptions: &GlobalOptions{
+			Console:   console.Fake(),
+			Repo:      repo,
+			authToken: "***",
+			host:      "github.com",
+		},
+		manifest: &manifest{
+			Issues: []issue{
+				{Title: "Release", Milestone: "v1.0"},
+			},
+		},
+	}
+
+	// No requests should be sent.
+	err = seedRepository(opts)
+	assert.EqualError(t, err, `issue "Release" references undeclared milestone "v1.0"`)
+}
+
+// bodyContains matches a request body containing all substrings.
+func bodyContains(substrings ...string) gock.MatchFunc {
+	return func(req *http.Request, _ *gock.Request) (bool, error) {
+		content, err := io.ReadAll(req.Body)
+		if err != nil {
+			return false, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(content))
+
+		for _, s := range substrings {
+			if !strings.Contains(string(content), s) {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+}
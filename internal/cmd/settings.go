@@ -0,0 +1,225 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/heaths/gh-template/internal/functions"
+	"github.com/heaths/gh-template/internal/git"
+	"github.com/spf13/cobra"
+)
+
+// settingsFlags adds flags for repository settings not supported by `gh repo create`, which are applied after creation if specified.
+func settingsFlags(cmd *cobra.Command, settings *repositorySettings) {
+	cmd.Flags().StringVar(&settings.DefaultBranch, "default-branch", "", "Rename the default branch to `name`")
+	cmd.Flags().StringSliceVar(&settings.Topics, "topic", nil, "Repository `topics` to set")
+	cmd.Flags().Bool("enable-discussions", false, "Enable discussions in the new repository")
+	cmd.Flags().Bool("enable-auto-merge", false, "Allow pull requests to merge automatically")
+	cmd.Flags().Bool("delete-branch-on-merge", false, "Delete head branches when pull requests are merged")
+	cmd.Flags().Bool("allow-merge-commit", false, "Allow merge commits for pull requests; pass =false to disallow")
+	cmd.Flags().Bool("allow-squash-merge", false, "Allow squash merging pull requests; pass =false to disallow")
+	cmd.Flags().Bool("allow-rebase-merge", false, "Allow rebase merging pull requests; pass =false to disallow")
+}
+
+// parseSettingsFlags sets boolean settings only if their flags were passed so the template manifest is not overridden otherwise.
+func parseSettingsFlags(cmd *cobra.Command, settings *repositorySettings) {
+	flags := cmd.Flags()
+	for name, value := range map[string]**bool{
+		"enable-discussions":     &settings.HasDiscussions,
+		"enable-auto-merge":      &settings.AllowAutoMerge,
+		"delete-branch-on-merge": &settings.DeleteBranchOnMerge,
+		"allow-merge-commit":     &settings.AllowMergeCommit,
+		"allow-squash-merge":     &settings.AllowSquashMerge,
+		"allow-rebase-merge":     &settings.AllowRebaseMerge,
+	} {
+		if flags.Changed(name) {
+			b, _ := flags.GetBool(name)
+			*value = &b
+		}
+	}
+}
+
+// repositorySettings are settings applied to a new repository after it is created.
+// String values may contain templates using the same parameters as template files.
+type repositorySettings struct {
+	DefaultBranch string   `yaml:"defaultBranch" json:"-"`
+	Topics        []string `yaml:"topics" json:"-"`
+
+	HasDiscussions      *bool `yaml:"hasDiscussions" json:"has_discussions,omitempty"`
+	AllowMergeCommit    *bool `yaml:"allowMergeCommit" json:"allow_merge_commit,omitempty"`
+	AllowSquashMerge    *bool `yaml:"allowSquashMerge" json:"allow_squash_merge,omitempty"`
+	AllowRebaseMerge    *bool `yaml:"allowRebaseMerge" json:"allow_rebase_merge,omitempty"`
+	AllowAutoMerge      *bool `yaml:"allowAutoMerge" json:"allow_auto_merge,omitempty"`
+	DeleteBranchOnMerge *bool `yaml:"deleteBranchOnMerge" json:"delete_branch_on_merge,omitempty"`
+
+	SquashMergeCommitTitle   string `yaml:"squashMergeCommitTitle" json:"squash_merge_commit_title,omitempty"`
+	SquashMergeCommitMessage string `yaml:"squashMergeCommitMessage" json:"squash_merge_commit_message,omitempty"`
+	MergeCommitTitle         string `yaml:"mergeCommitTitle" json:"merge_commit_title,omitempty"`
+	MergeCommitMessage       string `yaml:"mergeCommitMessage" json:"merge_commit_message,omitempty"`
+}
+
+// merge returns settings with any values set in other taking precedence.
+func (s repositorySettings) merge(other repositorySettings) repositorySettings {
+	if other.DefaultBranch != "" {
+		s.DefaultBranch = other.DefaultBranch
+	}
+	if other.Topics != nil {
+		s.Topics = other.Topics
+	}
+
+	for _, v := range []struct{ dst, src **bool }{
+		{&s.HasDiscussions, &other.HasDiscussions},
+		{&s.AllowMergeCommit, &other.AllowMergeCommit},
+		{&s.AllowSquashMerge, &other.AllowSquashMerge},
+		{&s.AllowRebaseMerge, &other.AllowRebaseMerge},
+		{&s.AllowAutoMerge, &other.AllowAutoMerge},
+		{&s.DeleteBranchOnMerge, &other.DeleteBranchOnMerge},
+	} {
+		if *v.src != nil {
+			*v.dst = *v.src
+		}
+	}
+
+	return s
+}
+
+// expand renders settings values as templates.
+func (s *repositorySettings) expand(opts *applyOptions) (err error) {
+	for _, v := range []*string{
+		&s.DefaultBranch,
+		&s.SquashMergeCommitTitle,
+		&s.SquashMergeCommitMessage,
+		&s.MergeCommitTitle,
+		&s.MergeCommitMessage,
+	} {
+		if *v, err = expand(*v, opts); err != nil {
+			return
+		}
+	}
+
+	topics := make([]string, len(s.Topics))
+	var invalid []string
+	for i, topic := range s.Topics {
+		if topic, err = expand(topic, opts); err != nil {
+			return
+		}
+		if !topicPattern.MatchString(topic) {
+			invalid = append(invalid, strconv.Quote(topic))
+		}
+		topics[i] = topic
+	}
+	if len(invalid) > 0 {
+		return fmt.Errorf("invalid topics %s; topics must start with a lowercase letter or number, contain only lowercase letters, numbers, and hyphens, and be 50 characters or less", strings.Join(invalid, ", "))
+	}
+	s.Topics = topics
+
+	return
+}
+
+// topicPattern matches topics GitHub accepts.
+var topicPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,49}$`)
+
+// configureRepository applies settings declared by the template manifest and command line to the repository cloned to root.
+func configureRepository(root string, flags repositorySettings, opts *applyOptions) error {
+	settings := flags
+	if opts.manifest != nil && opts.manifest.Repository != nil {
+		settings = opts.manifest.Repository.merge(flags)
+	}
+
+	if err := settings.expand(opts); err != nil {
+		return fmt.Errorf("failed to expand repository settings: %w", err)
+	}
+
+	client, err := opts.restClient(opts.Repo.Host())
+	if err != nil {
+		return err
+	}
+
+	path := fmt.Sprintf("repos/%s/%s", opts.Repo.Owner(), opts.Repo.Name())
+
+	body, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(body, []byte("{}")) {
+		opts.logVerbose("updating repository settings: %s", body)
+		if err = client.Patch(path, bytes.NewReader(body), nil); err != nil {
+			return fmt.Errorf("failed to update repository settings: %w", err)
+		}
+	}
+
+	if len(settings.Topics) > 0 {
+		body, err = json.Marshal(map[string][]string{"names": settings.Topics})
+		if err != nil {
+			return err
+		}
+
+		opts.logVerbose("setting topics: %s", strings.Join(settings.Topics, ", "))
+		if err = client.Put(path+"/topics", bytes.NewReader(body), nil); err != nil {
+			return fmt.Errorf("failed to set topics: %w", err)
+		}
+	}
+
+	if settings.DefaultBranch != "" {
+		from, err := git.Branch(root)
+		if err != nil {
+			return fmt.Errorf("failed to rename default branch to %s: %w", settings.DefaultBranch, err)
+		}
+		if from == settings.DefaultBranch {
+			return nil
+		}
+
+		body, err = json.Marshal(map[string]string{"new_name": settings.DefaultBranch})
+		if err != nil {
+			return err
+		}
+
+		opts.logVerbose("renaming default branch %s to %s", from, settings.DefaultBranch)
+		if err = client.Post(fmt.Sprintf("%s/branches/%s/rename", path, url.PathEscape(from)), bytes.NewReader(body), nil); err != nil {
+			return fmt.Errorf("failed to rename default branch to %s: %w", settings.DefaultBranch, err)
+		}
+
+		if err = git.RenameBranch(root, from, settings.DefaultBranch); err != nil {
+			return fmt.Errorf("failed to rename local branch to %s: %w", settings.DefaultBranch, err)
+		}
+		opts.params["github.defaultBranch"] = settings.DefaultBranch
+	}
+
+	return nil
+}
+
+// expand renders s as a template using parameters and string functions.
+func expand(s string, opts *applyOptions) (string, error) {
+	if s == "" {
+		return s, nil
+	}
+
+	t := template.New("").Funcs(template.FuncMap{
+		"param": functions.ParamFunc(opts.stdin(), opts.Console.Stderr(), opts.isInteractive(), opts.params),
+	})
+	t = t.Funcs(functions.StringFuncs(opts.language))
+	if opts.leftDelim != "" && opts.rightDelim != "" {
+		t = t.Delims(opts.leftDelim, opts.rightDelim)
+	}
+
+	t, err := t.Parse(s)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err = t.Execute(&buf, nil); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
@@ -0,0 +1,211 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cli/go-gh/pkg/repository"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/heaths/gh-template/internal/git"
+	"github.com/heaths/go-console"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/language"
+	"gopkg.in/h2non/gock.v1"
+	"gopkg.in/yaml.v3"
+)
+
+func TestConfigureRepository(t *testing.T) {
+	t.Cleanup(gock.Off)
+
+	root := t.TempDir()
+	repo, err := gogit.PlainInit(root, false)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(root, "README.md"), []byte("# Example\n"), 0o644))
+	worktree, err := repo.Worktree()
+	require.NoError(t, err)
+	_, err = worktree.Add("README.md")
+	require.NoError(t, err)
+	_, err = worktree.Commit("Initial commit", &gogit.CommitOptions{
+		Author: &object.Signature{Name: "Test User", Email: "test@domain.com", When: time.Now()},
+	})
+	require.NoError(t, err)
+
+	branch, err := git.Branch(root)
+	require.NoError(t, err)
+
+	gock.New("https://api.github.com").
+		Patch("/repos/heaths/example").
+		BodyString(`{
+			"has_discussions": true,
+			"allow_merge_commit": false,
+			"delete_branch_on_merge": true,
+			"squash_merge_commit_title": "PR_TITLE"
+		}`).
+		Reply(200).
+		JSON(`{}`)
+	gock.New("https://api.github.com").
+		Put("/repos/heaths/example/topics").
+		BodyString(`{"names": ["go", "example-service"]}`).
+		Reply(200).
+		JSON(`{}`)
+	gock.New("https://api.github.com").
+		Post("/repos/heaths/example/branches/" + branch + "/rename").
+		BodyString(`{"new_name": "trunk"}`).
+		Reply(201).
+		JSON(`{}`)
+
+	var m manifest
+	require.NoError(t, yaml.Unmarshal([]byte(`
+repository:
+  defaultBranch: main
+  topics:
+  - go
+  - '{{param "name" | kebabcase}}'
+  hasDiscussions: true
+  allowMergeCommit: true
+  squashMergeCommitTitle: PR_TITLE
+`), &m))
+
+	allowMergeCommit := false
+	deleteBranchOnMerge := true
+	flags := repositorySettings{
+		DefaultBranch:       "trunk",
+		AllowMergeCommit:    &allowMergeCommit,
+		DeleteBranchOnMerge: &deleteBranchOnMerge,
+	}
+
+	repoInfo, err := repository.Parse("heaths/example")
+	require.NoError(t, err)
+
+	opts := &applyOptions{
+		GlobalOptions: &GlobalOptions{
+			Console:   console.Fake(),
+			Repo:      repoInfo,
+			authToken: "***",
+			host:      "github.com",
+		},
+		language: language.English,
+		manifest: &m,
+		params: map[string]string{
+			"name": "ExampleService",
+		},
+	}
+
+	err = configureRepository(root, flags, opts)
+	require.NoError(t, err)
+	assert.True(t, gock.IsDone(), pendingMocks(gock.Pending()))
+
+	branch, err = git.Branch(root)
+	require.NoError(t, err)
+	assert.Equal(t, "trunk", branch)
+	assert.Equal(t, "trunk", opts.params["github.defaultBranch"])
+	assert.Equal(t, []string{"go", `{{param "name" | kebabcase}}`}, m.Repository.Topics)
+}
+
+func TestConfigureRepository_none(t *testing.T) {
+	repoInfo, err := repository.Parse("heaths/example")
+	require.NoError(t, err)
+
+	opts := &applyOptions{
+		GlobalOptions: &GlobalOptions{
+			Console:   console.Fake(),
+			Repo:      repoInfo,
+			authToken: "***",
+			host:      "github.com",
+		},
+		manifest: &manifest{},
+	}
+
+	// No requests should be sent.
+	err = configureRepository(t.TempDir(), repositorySettings{}, opts)
+	assert.NoError(t, err)
+}
+
+func TestRepositorySettings_expandTopics(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		topics  []string
+		want    []string
+		wantErr string
+	}{
+		{
+			name:   "valid",
+			topics: []string{"go", `{{param "name" | kebabcase}}`, "v2"},
+			want:   []string{"go", "example-service", "v2"},
+		},
+		{
+			name:    "invalid",
+			topics:  []string{"Go", "go", "-cli", "dot.net", `{{param "name"}}`},
+			wantErr: `invalid topics "Go", "-cli", "dot.net", "ExampleService"; topics must start with a lowercase letter or number, contain only lowercase letters, numbers, and hyphens, and be 50 characters or less`,
+		},
+		{
+			name:    "too long",
+			topics:  []string{strings.Repeat("a", 51)},
+			wantErr: `invalid topics "` + strings.Repeat("a", 51) + `"; topics must start with a lowercase letter or number, contain only lowercase letters, numbers, and hyphens, and be 50 characters or less`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			opts := &applyOptions{
+				GlobalOptions: &GlobalOptions{
+					Console: console.Fake(),
+				},
+				language: language.English,
+				params: map[string]string{
+					"name": "ExampleService",
+				},
+			}
+
+			s := &repositorySettings{Topics: tt.topics}
+			err := s.expand(opts)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, s.Topics)
+		})
+	}
+}
+
+func TestExpand_notInteractive(t *testing.T) {
+	t.Parallel()
+
+	// Prompts are written to stderr, so do not prompt if only stdin is a terminal.
+	opts := &applyOptions{
+		GlobalOptions: &GlobalOptions{
+			Console: console.Fake(
+				console.WithStdin(bytes.NewBufferString("example\n")),
+				console.WithStdinTTY(true),
+			),
+		},
+		params: map[string]string{},
+	}
+
+	_, err := expand(`{{param "name"}}`, opts)
+	assert.EqualError(t, err, `template: :1:2: executing "" at <param "name">: error calling param: cannot prompt for parameter "name"`)
+
+	opts.Console = console.Fake(
+		console.WithStdin(bytes.NewBufferString("example\n")),
+		console.WithStdinTTY(true),
+		console.WithStderrTTY(true),
+	)
+	got, err := expand(`{{param "name"}}`, opts)
+	require.NoError(t, err)
+	assert.Equal(t, "example", got)
+}
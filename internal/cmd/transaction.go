@@ -0,0 +1,453 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/heaths/gh-template/internal/git"
+)
+
+var errInterrupted = errors.New("interrupted")
+
+type changeKind int
+
+const (
+	changeAdd changeKind = iota
+	changeModify
+	changeDelete
+)
+
+// change is a file in the staging directory that differs from the working tree.
+type change struct {
+	path string
+	kind changeKind
+}
+
+// transaction commits changes from a staging directory to the working tree,
+// backing up original files so they can be restored.
+type transaction struct {
+	root    string
+	staging string
+	backup  string
+
+	// ctx is cancelled if the command is interrupted.
+	ctx context.Context
+
+	// Files and directories already committed, in order.
+	committed []change
+	dirs      []string
+}
+
+// transact copies root into a staging directory and calls fn to modify the staged copy.
+// Changes are committed to root only if fn succeeds, and any partially committed changes
+// are rolled back if committing fails or is interrupted.
+func transact(root string, opts *applyOptions, fn func(staging string) error) (err error) {
+	temp, err := os.MkdirTemp("", "gh-template-")
+	if err != nil {
+		return
+	}
+	defer os.RemoveAll(temp)
+
+	t := &transaction{
+		root:    root,
+		staging: filepath.Join(temp, "staging"),
+		backup:  filepath.Join(temp, "backup"),
+		ctx:     opts.context(),
+	}
+
+	original, err := stageTree(root, t.staging)
+	if err != nil {
+		return fmt.Errorf("failed to stage changes: %w", err)
+	}
+
+	// Nothing in root has changed until committing, so stop if interrupted while rendering e.g., while
+	// prompting for parameters; otherwise, roll back any changes already committed. Prompts stop reading
+	// once interrupted, so fn has returned and no longer writes to staging before it is removed.
+	err = fn(t.staging)
+	if t.ctx.Err() != nil {
+		return errInterrupted
+	} else if err != nil {
+		return
+	}
+
+	changes, err := diffTrees(original, root, t.staging)
+	if err != nil {
+		return
+	}
+
+	if err = t.commit(changes, opts); err != nil {
+		if rollbackErr := t.rollback(opts); rollbackErr != nil {
+			return fmt.Errorf("%v; failed to restore original files: %w", err, rollbackErr)
+		}
+		return fmt.Errorf("failed to apply changes; original files restored: %w", err)
+	}
+
+	// Keep any previous snapshot if nothing changed.
+	if len(changes) == 0 {
+		return
+	}
+
+	if dir, top, snapshotErr := undoPath(root); errors.Is(snapshotErr, git.ErrNotRepository) {
+		opts.logVerbose("cannot save snapshot to undo changes: %v", snapshotErr)
+	} else if snapshotErr != nil {
+		opts.logWarning("failed to save snapshot to undo changes: %v", snapshotErr)
+	} else if snapshotErr = saveSnapshot(dir, top, t, changes); snapshotErr != nil {
+		opts.logWarning("failed to save snapshot to undo changes: %v", snapshotErr)
+	}
+
+	return
+}
+
+func (t *transaction) commit(changes []change, opts *applyOptions) error {
+	for _, c := range changes {
+		if t.ctx.Err() != nil {
+			return errInterrupted
+		}
+
+		target := filepath.Join(t.root, c.path)
+		if c.kind != changeAdd {
+			if err := copyFile(target, filepath.Join(t.backup, c.path)); err != nil {
+				return err
+			}
+		}
+
+		switch c.kind {
+		case changeAdd, changeModify:
+			opts.logVerbose("writing %q", filepath.ToSlash(c.path))
+			if err := t.mkdirAll(filepath.Dir(target)); err != nil {
+				return err
+			}
+			t.committed = append(t.committed, c)
+			if err := writeFileAtomic(filepath.Join(t.staging, c.path), target); err != nil {
+				return err
+			}
+
+		case changeDelete:
+			opts.logVerbose("deleting %q", filepath.ToSlash(c.path))
+			t.committed = append(t.committed, c)
+			if err := os.Remove(target); err != nil {
+				return err
+			}
+			removeEmptyDirs(t.root, filepath.Dir(target))
+		}
+	}
+
+	return nil
+}
+
+func (t *transaction) rollback(opts *applyOptions) error {
+	var errs []error
+	for i := len(t.committed) - 1; i >= 0; i-- {
+		c := t.committed[i]
+		target := filepath.Join(t.root, c.path)
+
+		var err error
+		switch c.kind {
+		case changeAdd:
+			if err = os.Remove(target); errors.Is(err, fs.ErrNotExist) {
+				err = nil
+			}
+		default:
+			opts.logVerbose("restoring %q", filepath.ToSlash(c.path))
+			if err = os.MkdirAll(filepath.Dir(target), 0o755); err == nil {
+				err = writeFileAtomic(filepath.Join(t.backup, c.path), target)
+			}
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for i := len(t.dirs) - 1; i >= 0; i-- {
+		os.Remove(t.dirs[i])
+	}
+
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// mkdirAll creates dir and any parents, tracking which were created so they can be removed during rollback.
+func (t *transaction) mkdirAll(dir string) error {
+	var missing []string
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(d); err == nil {
+			break
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		missing = append(missing, d)
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+
+	for i := len(missing) - 1; i >= 0; i-- {
+		if err := os.Mkdir(missing[i], 0o755); err != nil && !errors.Is(err, fs.ErrExist) {
+			return err
+		}
+		t.dirs = append(t.dirs, missing[i])
+	}
+
+	return nil
+}
+
+// diffTrees returns files added, modified, or deleted in staging compared to the original files in root, sorted by path.
+func diffTrees(original map[string]fs.FileInfo, root, staging string) ([]change, error) {
+	staged, err := listFiles(staging)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]change, 0)
+	for path, info := range staged {
+		originalInfo, ok := original[path]
+		if !ok {
+			// Files not staged e.g., ignored files, are modified only if written to staging.
+			if originalInfo, err = os.Lstat(filepath.Join(root, path)); errors.Is(err, fs.ErrNotExist) {
+				changes = append(changes, change{path: path, kind: changeAdd})
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+		}
+
+		same, err := sameFile(filepath.Join(root, path), originalInfo, filepath.Join(staging, path), info)
+		if err != nil {
+			return nil, err
+		}
+		if !same {
+			changes = append(changes, change{path: path, kind: changeModify})
+		}
+	}
+
+	for path := range original {
+		if _, ok := staged[path]; !ok {
+			changes = append(changes, change{path: path, kind: changeDelete})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].path < changes[j].path
+	})
+
+	return changes, nil
+}
+
+// listFiles returns all files under root relative to root, excluding repositories.
+func listFiles(root string) (map[string]fs.FileInfo, error) {
+	files := make(map[string]fs.FileInfo)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if name := d.Name(); path != root && (name == ".git" || name == ".hg") {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		files[rel] = info
+		return nil
+	})
+
+	return files, err
+}
+
+func sameFile(a string, aInfo fs.FileInfo, b string, bInfo fs.FileInfo) (bool, error) {
+	if aInfo.Mode() != bInfo.Mode() || aInfo.Size() != bInfo.Size() {
+		return false, nil
+	}
+
+	if aInfo.Mode()&fs.ModeSymlink != 0 {
+		aTarget, err := os.Readlink(a)
+		if err != nil {
+			return false, err
+		}
+		bTarget, err := os.Readlink(b)
+		if err != nil {
+			return false, err
+		}
+		return aTarget == bTarget, nil
+	}
+
+	aContent, err := os.ReadFile(a)
+	if err != nil {
+		return false, err
+	}
+	bContent, err := os.ReadFile(b)
+	if err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(aContent, bContent), nil
+}
+
+// stageTree copies files in root that templates may change into staging and returns them.
+// Within a repository, only tracked files and untracked files not ignored are staged;
+// otherwise, all files excluding repositories are staged.
+func stageTree(root, staging string) (map[string]fs.FileInfo, error) {
+	names, err := git.Files(root)
+	if errors.Is(err, git.ErrNotRepository) {
+		if err = copyTree(root, staging); err != nil {
+			return nil, err
+		}
+		return listFiles(root)
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err = os.MkdirAll(staging, 0o755); err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]fs.FileInfo, len(names))
+	for _, name := range names {
+		info, err := os.Lstat(filepath.Join(root, name))
+		if errors.Is(err, fs.ErrNotExist) {
+			// Tracked files already deleted from the working tree.
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if info.IsDir() {
+			// Submodules are tracked as directories.
+			continue
+		}
+
+		if err = copyFile(filepath.Join(root, name), filepath.Join(staging, name)); err != nil {
+			return nil, err
+		}
+		files[name] = info
+	}
+
+	return files, nil
+}
+
+// copyTree copies all files from src into dst, excluding repositories.
+func copyTree(src, dst string) error {
+	files, err := listFiles(src)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(dst, 0o755); err != nil {
+		return err
+	}
+
+	for path := range files {
+		if err = copyFile(filepath.Join(src, path), filepath.Join(dst, path)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// copyFile copies src to dst including its permissions, or the link if src is a symbolic link.
+func copyFile(src, dst string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+
+	if info.Mode()&fs.ModeSymlink != 0 {
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, dst)
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+
+	if err = out.Close(); err != nil {
+		return err
+	}
+
+	return os.Chmod(dst, info.Mode().Perm())
+}
+
+// writeFileAtomic replaces dst with a copy of src by renaming a temporary file in the same directory.
+func writeFileAtomic(src, dst string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(dst), ".gh-template-*")
+	if err != nil {
+		return err
+	}
+	temp.Close()
+
+	if info.Mode()&fs.ModeSymlink != 0 {
+		// Replace the unique temporary file with the link.
+		if err = os.Remove(temp.Name()); err != nil {
+			return err
+		}
+	}
+
+	if err = copyFile(src, temp.Name()); err != nil {
+		os.Remove(temp.Name())
+		return err
+	}
+
+	if err = os.Rename(temp.Name(), dst); err != nil {
+		os.Remove(temp.Name())
+		return err
+	}
+
+	return nil
+}
+
+// removeEmptyDirs removes dir and any empty parents up to but excluding root.
+func removeEmptyDirs(root, dir string) {
+	root = filepath.Clean(root)
+	for dir = filepath.Clean(dir); dir != root && dir != "." && dir != string(filepath.Separator); dir = filepath.Dir(dir) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+	}
+}
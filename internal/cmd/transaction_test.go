@@ -0,0 +1,358 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/heaths/go-console"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransact(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		fn      func(staging string) error
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "commits",
+			fn: func(staging string) error {
+				if err := os.WriteFile(filepath.Join(staging, "README.md"), []byte("# Rendered\n"), 0o644); err != nil {
+					return err
+				}
+				if err := os.MkdirAll(filepath.Join(staging, "cmd", "app"), 0o755); err != nil {
+					return err
+				}
+				if err := os.WriteFile(filepath.Join(staging, "cmd", "app", "main.go"), []byte("package main\n"), 0o644); err != nil {
+					return err
+				}
+				return os.RemoveAll(filepath.Join(staging, "_partials"))
+			},
+			want: map[string]string{
+				"README.md":        "# Rendered\n",
+				"LICENSE":          "MIT\n",
+				"cmd/app/main.go":  "package main\n",
+				".git/HEAD":        "ref: refs/heads/main\n",
+				"_partials/header": "",
+			},
+		},
+		{
+			name: "fails",
+			fn: func(staging string) error {
+				if err := os.WriteFile(filepath.Join(staging, "README.md"), []byte("# Rendered\n"), 0o644); err != nil {
+					return err
+				}
+				if err := os.RemoveAll(filepath.Join(staging, "_partials")); err != nil {
+					return err
+				}
+				return errors.New("template: README.md: unexpected EOF")
+			},
+			want: map[string]string{
+				"README.md":        "# {{param \"name\"}}\n",
+				"LICENSE":          "MIT\n",
+				".git/HEAD":        "ref: refs/heads/main\n",
+				"_partials/header": "// header\n",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			root := t.TempDir()
+			for name, content := range map[string]string{
+				"README.md":        "# {{param \"name\"}}\n",
+				"LICENSE":          "MIT\n",
+				".git/HEAD":        "ref: refs/heads/main\n",
+				"_partials/header": "// header\n",
+			} {
+				path := filepath.Join(root, name)
+				require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+				require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+			}
+
+			opts := &applyOptions{
+				GlobalOptions: &GlobalOptions{},
+			}
+
+			err := transact(root, opts, tt.fn)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			for name, content := range tt.want {
+				got, err := os.ReadFile(filepath.Join(root, name))
+				if content == "" {
+					assert.ErrorIs(t, err, fs.ErrNotExist, name)
+					continue
+				}
+				require.NoError(t, err, name)
+				assert.Equal(t, content, string(got), name)
+			}
+		})
+	}
+}
+
+func TestTransaction_rollback(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.txt"), []byte("a"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "b.txt"), []byte("b"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "c.txt"), []byte("c"), 0o644))
+
+	temp := t.TempDir()
+	staging := filepath.Join(temp, "staging")
+	original, err := stageTree(root, staging)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(staging, "a.txt"), []byte("A"), 0o644))
+	require.NoError(t, os.Remove(filepath.Join(staging, "b.txt")))
+	require.NoError(t, os.MkdirAll(filepath.Join(staging, "d"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(staging, "d", "d.txt"), []byte("d"), 0o644))
+
+	changes, err := diffTrees(original, root, staging)
+	require.NoError(t, err)
+	assert.Equal(t, []change{
+		{path: "a.txt", kind: changeModify},
+		{path: "b.txt", kind: changeDelete},
+		{path: filepath.Join("d", "d.txt"), kind: changeAdd},
+	}, changes)
+
+	tx := &transaction{
+		root:    root,
+		staging: staging,
+		backup:  filepath.Join(temp, "backup"),
+		ctx:     context.Background(),
+	}
+	opts := &applyOptions{
+		GlobalOptions: &GlobalOptions{},
+	}
+
+	require.NoError(t, tx.commit(changes, opts))
+	require.NoError(t, tx.rollback(opts))
+
+	for name, content := range map[string]string{
+		"a.txt": "a",
+		"b.txt": "b",
+		"c.txt": "c",
+	} {
+		got, err := os.ReadFile(filepath.Join(root, name))
+		require.NoError(t, err, name)
+		assert.Equal(t, content, string(got), name)
+	}
+
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(filepath.Join(root, "b.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, fs.FileMode(0o755), info.Mode().Perm())
+	}
+
+	_, err = os.Stat(filepath.Join(root, "d"))
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestTransaction_interrupted(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.txt"), []byte("a"), 0o644))
+
+	temp := t.TempDir()
+	staging := filepath.Join(temp, "staging")
+	original, err := stageTree(root, staging)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(staging, "a.txt"), []byte("A"), 0o644))
+
+	changes, err := diffTrees(original, root, staging)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tx := &transaction{
+		root:    root,
+		staging: staging,
+		backup:  filepath.Join(temp, "backup"),
+		ctx:     ctx,
+	}
+
+	err = tx.commit(changes, &applyOptions{GlobalOptions: &GlobalOptions{}})
+	assert.ErrorIs(t, err, errInterrupted)
+
+	got, err := os.ReadFile(filepath.Join(root, "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "a", string(got))
+}
+
+func TestTransact_interrupted(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.txt"), []byte("a"), 0o644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	opts := &applyOptions{
+		GlobalOptions: &GlobalOptions{
+			Context: ctx,
+		},
+	}
+
+	// Simulate being interrupted while prompting for parameters; the prompt stops reading before staging is removed.
+	stdin, w := io.Pipe()
+	t.Cleanup(func() { w.Close() })
+
+	var stageErr error
+	err := transact(root, opts, func(staging string) error {
+		cancel()
+		_, err := (&contextReader{ctx: opts.context(), r: stdin}).Read(make([]byte, 1))
+		stageErr = os.WriteFile(filepath.Join(staging, "a.txt"), []byte("b"), 0o644)
+		return err
+	})
+	assert.ErrorIs(t, err, errInterrupted)
+	assert.NoError(t, stageErr)
+
+	got, err := os.ReadFile(filepath.Join(root, "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "a", string(got))
+}
+
+func TestTransact_stagesTrackedFiles(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	_, err := gogit.PlainInit(root, false)
+	require.NoError(t, err)
+
+	for name, content := range map[string]string{
+		".gitignore":       "bin/\n",
+		"README.md":        "# {{param \"name\"}}\n",
+		"bin/app":          "ignored\n",
+		"bin/cache/1.bin":  "ignored\n",
+		"docs/overview.md": "{{param \"name\"}}\n",
+	} {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+
+	opts := &applyOptions{
+		GlobalOptions: &GlobalOptions{},
+	}
+
+	var staged []string
+	err = transact(root, opts, func(staging string) error {
+		files, err := listFiles(staging)
+		if err != nil {
+			return err
+		}
+		for name := range files {
+			staged = append(staged, filepath.ToSlash(name))
+		}
+
+		// Rendering may still write to ignored paths.
+		if err := os.MkdirAll(filepath.Join(staging, "bin"), 0o755); err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(staging, "bin", "app"), []byte("rendered\n"), 0o644)
+	})
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{".gitignore", "README.md", "docs/overview.md"}, staged)
+
+	for name, content := range map[string]string{
+		"bin/app":         "rendered\n",
+		"bin/cache/1.bin": "ignored\n",
+	} {
+		got, err := os.ReadFile(filepath.Join(root, name))
+		require.NoError(t, err, name)
+		assert.Equal(t, content, string(got), name)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	t.Parallel()
+
+	src := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(src, "a.txt"), []byte("a"), 0o644))
+
+	dst := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dst, "a.txt"), []byte("old"), 0o644))
+
+	require.NoError(t, writeFileAtomic(filepath.Join(src, "a.txt"), filepath.Join(dst, "a.txt")))
+
+	if runtime.GOOS != "windows" {
+		require.NoError(t, os.Symlink("a.txt", filepath.Join(src, "b.txt")))
+		require.NoError(t, writeFileAtomic(filepath.Join(src, "b.txt"), filepath.Join(dst, "b.txt")))
+
+		target, err := os.Readlink(filepath.Join(dst, "b.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, "a.txt", target)
+	}
+
+	got, err := os.ReadFile(filepath.Join(dst, "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "a", string(got))
+
+	// No temporary files should be left behind.
+	entries, err := os.ReadDir(dst)
+	require.NoError(t, err)
+	for _, entry := range entries {
+		assert.NotContains(t, entry.Name(), ".gh-template-")
+	}
+}
+
+func TestTransact_keepsIgnoredFiles(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	_, err := gogit.PlainInit(root, false)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".gitignore"), []byte(".env\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".env"), []byte("SECRET=existing\n"), 0o644))
+
+	src := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(src, ".env"), []byte("SECRET=template\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "README.md"), []byte("# Example\n"), 0o644))
+
+	strategies, err := parseStrategies(nil, nil)
+	require.NoError(t, err)
+
+	opts := &applyOptions{
+		GlobalOptions: &GlobalOptions{
+			Console: console.Fake(),
+		},
+		root: root,
+	}
+	err = transact(root, opts, func(staging string) error {
+		return mergeTree(src, staging, opts.rootDir(), strategies, opts)
+	})
+	require.NoError(t, err)
+
+	for name, content := range map[string]string{
+		".env":      "SECRET=existing\n",
+		"README.md": "# Example\n",
+	} {
+		got, err := os.ReadFile(filepath.Join(root, name))
+		require.NoError(t, err, name)
+		assert.Equal(t, content, string(got), name)
+	}
+}
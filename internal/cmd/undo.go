@@ -0,0 +1,218 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/heaths/gh-template/internal/git"
+)
+
+// undoDir is the directory relative to the .git directory where a snapshot of the last apply is saved.
+const undoDir = "gh-template/undo"
+
+// snapshot records changes made by the last apply so they can be undone.
+type snapshot struct {
+	// Root is the directory to which changes were applied relative to the root of the working tree,
+	// so the snapshot remains valid if the repository is moved.
+	Root    string          `json:"root"`
+	Changes []snapshotEntry `json:"changes"`
+}
+
+type snapshotEntry struct {
+	Path string     `json:"path"`
+	Kind changeKind `json:"kind"`
+
+	// Hash is the SHA-256 hash of the applied file, or empty if the file was deleted.
+	Hash string `json:"hash,omitempty"`
+}
+
+// String returns the name of the change used when saving snapshots.
+func (k changeKind) String() string {
+	switch k {
+	case changeAdd:
+		return "add"
+	case changeModify:
+		return "modify"
+	case changeDelete:
+		return "delete"
+	default:
+		return fmt.Sprintf("changeKind(%d)", int(k))
+	}
+}
+
+func (k changeKind) MarshalText() ([]byte, error) {
+	switch k {
+	case changeAdd, changeModify, changeDelete:
+		return []byte(k.String()), nil
+	default:
+		return nil, fmt.Errorf("unsupported change %d", int(k))
+	}
+}
+
+func (k *changeKind) UnmarshalText(text []byte) error {
+	for _, kind := range []changeKind{changeAdd, changeModify, changeDelete} {
+		if kind.String() == string(text) {
+			*k = kind
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported change %q", text)
+}
+
+// undoPath gets the snapshot directory and the root of the working tree for the repository containing root.
+func undoPath(root string) (dir, top string, err error) {
+	if dir, err = git.Dir(root); err != nil {
+		return
+	}
+	if top, err = git.Root(root); err != nil {
+		return
+	}
+
+	return filepath.Join(dir, filepath.FromSlash(undoDir)), top, nil
+}
+
+// saveSnapshot saves original files backed up by t and hashes of committed files to dir,
+// replacing any previous snapshot. The root of t is saved relative to top, the root of the working tree.
+func saveSnapshot(dir, top string, t *transaction, changes []change) error {
+	abs, err := filepath.Abs(t.root)
+	if err != nil {
+		return err
+	}
+	root, err := filepath.Rel(top, abs)
+	if err != nil {
+		return err
+	}
+
+	if err = os.RemoveAll(dir); err != nil {
+		return err
+	}
+	if err = os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	s := snapshot{
+		Root:    filepath.ToSlash(root),
+		Changes: make([]snapshotEntry, 0, len(changes)),
+	}
+	for _, c := range changes {
+		entry := snapshotEntry{
+			Path: filepath.ToSlash(c.path),
+			Kind: c.kind,
+		}
+
+		if c.kind != changeAdd {
+			if err = copyFile(filepath.Join(t.backup, c.path), filepath.Join(dir, "files", c.path)); err != nil {
+				return err
+			}
+		}
+
+		if c.kind != changeDelete {
+			if entry.Hash, err = hashFile(filepath.Join(t.root, c.path)); err != nil {
+				return err
+			}
+		}
+
+		s.Changes = append(s.Changes, entry)
+	}
+
+	content, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, "snapshot.json"), content, 0o644)
+}
+
+// undo restores files changed by the last apply from the snapshot in dir to the working tree rooted at top.
+// It refuses to restore anything if any of those files changed since.
+func undo(dir, top string, opts *applyOptions) error {
+	content, err := os.ReadFile(filepath.Join(dir, "snapshot.json"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("nothing to undo")
+		}
+		return err
+	}
+
+	var s snapshot
+	if err = json.Unmarshal(content, &s); err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	root := filepath.Join(top, filepath.FromSlash(s.Root))
+
+	var modified []string
+	for _, entry := range s.Changes {
+		hash, err := hashFile(filepath.Join(root, filepath.FromSlash(entry.Path)))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		if hash != entry.Hash {
+			modified = append(modified, entry.Path)
+		}
+	}
+	if len(modified) > 0 {
+		return fmt.Errorf("cannot undo since files were modified after apply:\n  %s", strings.Join(modified, "\n  "))
+	}
+
+	for i := len(s.Changes) - 1; i >= 0; i-- {
+		entry := s.Changes[i]
+		target := filepath.Join(root, filepath.FromSlash(entry.Path))
+
+		switch entry.Kind {
+		case changeAdd:
+			opts.logVerbose("deleting %q", entry.Path)
+			if err = os.Remove(target); err != nil {
+				return err
+			}
+			removeEmptyDirs(root, filepath.Dir(target))
+
+		default:
+			opts.logVerbose("restoring %q", entry.Path)
+			if err = os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			if err = writeFileAtomic(filepath.Join(dir, "files", filepath.FromSlash(entry.Path)), target); err != nil {
+				return err
+			}
+		}
+	}
+
+	return os.RemoveAll(dir)
+}
+
+// hashFile returns the hex-encoded SHA-256 hash of a file's permissions and content, or the target of a symbolic link.
+func hashFile(path string) (string, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%o\x00", info.Mode())
+
+	if info.Mode()&fs.ModeSymlink != 0 {
+		target, err := os.Readlink(path)
+		if err != nil {
+			return "", err
+		}
+		h.Write([]byte(target))
+	} else {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		h.Write(content)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,203 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/heaths/go-console"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUndo(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		modify  func(root string) error
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name: "restores",
+			want: map[string]string{
+				"README.md": "# {{param \"name\"}}\n",
+				"_partials": "// header\n",
+				"main.go":   "",
+			},
+		},
+		{
+			name: "modified",
+			modify: func(root string) error {
+				return os.WriteFile(filepath.Join(root, "README.md"), []byte("# Edited\n"), 0o644)
+			},
+			want: map[string]string{
+				"README.md": "# Edited\n",
+				"main.go":   "package main\n",
+			},
+			wantErr: "cannot undo since files were modified after apply:\n  README.md",
+		},
+		{
+			name: "recreated",
+			modify: func(root string) error {
+				return os.WriteFile(filepath.Join(root, "_partials"), []byte("// edited\n"), 0o644)
+			},
+			want: map[string]string{
+				"README.md": "# Example\n",
+				"_partials": "// edited\n",
+			},
+			wantErr: "cannot undo since files were modified after apply:\n  _partials",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			root := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(root, "README.md"), []byte("# {{param \"name\"}}\n"), 0o644))
+			require.NoError(t, os.WriteFile(filepath.Join(root, "_partials"), []byte("// header\n"), 0o644))
+
+			temp := t.TempDir()
+			staging := filepath.Join(temp, "staging")
+			original, err := stageTree(root, staging)
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile(filepath.Join(staging, "README.md"), []byte("# Example\n"), 0o644))
+			require.NoError(t, os.WriteFile(filepath.Join(staging, "main.go"), []byte("package main\n"), 0o644))
+			require.NoError(t, os.Remove(filepath.Join(staging, "_partials")))
+
+			changes, err := diffTrees(original, root, staging)
+			require.NoError(t, err)
+
+			tx := &transaction{
+				root:    root,
+				staging: staging,
+				backup:  filepath.Join(temp, "backup"),
+				ctx:     context.Background(),
+			}
+			opts := &applyOptions{
+				GlobalOptions: &GlobalOptions{},
+			}
+			require.NoError(t, tx.commit(changes, opts))
+
+			dir := filepath.Join(temp, "undo")
+			require.NoError(t, saveSnapshot(dir, root, tx, changes))
+
+			if tt.modify != nil {
+				require.NoError(t, tt.modify(root))
+			}
+
+			err = undo(dir, root, opts)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+				assert.NoDirExists(t, dir)
+			}
+
+			for name, content := range tt.want {
+				got, err := os.ReadFile(filepath.Join(root, name))
+				if content == "" {
+					assert.ErrorIs(t, err, fs.ErrNotExist, name)
+					continue
+				}
+				require.NoError(t, err, name)
+				assert.Equal(t, content, string(got), name)
+			}
+		})
+	}
+}
+
+func TestUndo_nothing(t *testing.T) {
+	t.Parallel()
+
+	err := undo(t.TempDir(), t.TempDir(), &applyOptions{GlobalOptions: &GlobalOptions{}})
+	assert.EqualError(t, err, "nothing to undo")
+}
+
+func TestSaveSnapshot(t *testing.T) {
+	t.Parallel()
+
+	top := t.TempDir()
+	root := filepath.Join(top, "src")
+	require.NoError(t, os.MkdirAll(root, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "main.go"), []byte("package main\n"), 0o644))
+
+	tx := &transaction{
+		root: root,
+		ctx:  context.Background(),
+	}
+	dir := filepath.Join(t.TempDir(), "undo")
+	require.NoError(t, saveSnapshot(dir, top, tx, []change{{path: "main.go", kind: changeAdd}}))
+
+	content, err := os.ReadFile(filepath.Join(dir, "snapshot.json"))
+	require.NoError(t, err)
+
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(content, &got))
+	assert.Equal(t, "src", got["root"])
+	if changes, ok := got["changes"].([]any); assert.True(t, ok) && assert.Len(t, changes, 1) {
+		assert.Equal(t, "add", changes[0].(map[string]any)["kind"])
+	}
+
+	var s snapshot
+	require.NoError(t, json.Unmarshal(content, &s))
+	assert.Equal(t, changeAdd, s.Changes[0].Kind)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"changes":[{"path":"a","kind":"rename"}]}`), &s))
+}
+
+func TestApply_undoLicense(t *testing.T) {
+	root := t.TempDir()
+	repo, err := gogit.PlainInit(root, false)
+	require.NoError(t, err)
+
+	cfg, err := repo.Config()
+	require.NoError(t, err)
+	cfg.User.Name = "Test User"
+	cfg.User.Email = "test@domain.com"
+	require.NoError(t, repo.SetConfig(cfg))
+
+	require.NoError(t, os.WriteFile(filepath.Join(root, "README.md"), []byte("# {{param \"name\"}}\n"), 0o644))
+
+	fake := console.Fake()
+	opts := &applyOptions{
+		GlobalOptions: &GlobalOptions{
+			Console: fake,
+		},
+		root:       root,
+		license:    "MIT",
+		addLicense: true,
+		force:      true,
+		params: map[string]string{
+			"name": "example",
+		},
+	}
+	require.NoError(t, apply(opts))
+	assert.FileExists(t, filepath.Join(root, "LICENSE"))
+
+	// Applying again changes nothing and keeps the previous snapshot.
+	opts.manifest = nil
+	require.NoError(t, apply(opts))
+
+	dir, top, err := undoPath(root)
+	require.NoError(t, err)
+	require.NoError(t, undo(dir, top, opts))
+
+	assert.NoFileExists(t, filepath.Join(root, "LICENSE"))
+	content, err := os.ReadFile(filepath.Join(root, "README.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "# {{param \"name\"}}\n", string(content))
+
+	_, stderr, _ := fake.Buffers()
+	assert.Empty(t, stderr.String())
+}
@@ -0,0 +1,119 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package functions
+
+import (
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+//go:embed licenses/*.txt
+var licenses embed.FS
+
+// LicenseFuncs returns functions to render bundled licenses using license as the default license,
+// if not empty, and the "git.name" parameter as the copyright holder.
+func LicenseFuncs(license string, params map[string]string) map[string]any {
+	return map[string]any{
+		"license": LicenseFunc(license, params),
+		"spdx":    SPDXFunc(license),
+	}
+}
+
+// Licenses returns the SPDX identifiers of all bundled licenses.
+func Licenses() []string {
+	entries, _ := licenses.ReadDir("licenses")
+	ids := make([]string, len(entries))
+	for i, entry := range entries {
+		ids[i] = strings.TrimSuffix(entry.Name(), ".txt")
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// LicenseID returns the SPDX identifier for a bundled license using a case-insensitive comparison.
+func LicenseID(name string) (string, error) {
+	for _, id := range Licenses() {
+		if strings.EqualFold(id, name) {
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported license %q; expected one of: %s", name, strings.Join(Licenses(), ", "))
+}
+
+// License renders the bundled license text for the SPDX identifier.
+func License(id string, year int, holder string) (string, error) {
+	id, err := LicenseID(id)
+	if err != nil {
+		return "", err
+	}
+
+	t, err := template.ParseFS(licenses, path.Join("licenses", id+".txt"))
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	err = t.Execute(&sb, struct {
+		Year   int
+		Holder string
+	}{
+		Year:   year,
+		Holder: holder,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return sb.String(), nil
+}
+
+// LicenseFunc returns a function that renders the license passed to it, or license if none was passed.
+// If neither was specified, the function returns an empty string.
+func LicenseFunc(license string, params map[string]string) func(...string) (string, error) {
+	return func(name ...string) (string, error) {
+		id, err := licenseName(license, name)
+		if id == "" || err != nil {
+			return "", err
+		}
+
+		holder := params["git.name"]
+		if holder == "" {
+			return "", fmt.Errorf("cannot render license without copyright holder; set git.name")
+		}
+
+		return License(id, DateFunc().Year(), holder)
+	}
+}
+
+// SPDXFunc returns a function that returns an SPDX-License-Identifier header for the license passed to it,
+// or license if none was passed. If neither was specified, the function returns an empty string.
+func SPDXFunc(license string) func(...string) (string, error) {
+	return func(name ...string) (string, error) {
+		id, err := licenseName(license, name)
+		if id == "" || err != nil {
+			return "", err
+		}
+
+		if id, err = LicenseID(id); err != nil {
+			return "", err
+		}
+
+		return "SPDX-License-Identifier: " + id, nil
+	}
+}
+
+func licenseName(license string, name []string) (string, error) {
+	switch len(name) {
+	case 0:
+		return license, nil
+	case 1:
+		return name[0], nil
+	default:
+		return "", fmt.Errorf("expected at most 1 license, got %d", len(name))
+	}
+}
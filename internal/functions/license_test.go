@@ -0,0 +1,119 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package functions
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLicenses(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, []string{
+		"Apache-2.0",
+		"BSD-2-Clause",
+		"BSD-3-Clause",
+		"ISC",
+		"MIT",
+		"Unlicense",
+	}, Licenses())
+}
+
+func TestLicenseFunc(t *testing.T) {
+	t.Parallel()
+
+	year := DateFunc().Year()
+	tests := []struct {
+		name     string
+		license  string
+		params   map[string]string
+		args     []string
+		wantLine string
+		wantErr  bool
+	}{
+		{
+			name: "mit",
+			params: map[string]string{
+				"git.name": "Heath Stewart",
+			},
+			args:     []string{"mit"},
+			wantLine: fmt.Sprintf("Copyright (c) %d Heath Stewart", year),
+		},
+		{
+			name:    "default",
+			license: "BSD-3-clause",
+			params: map[string]string{
+				"git.name": "Heath Stewart",
+				"license":  "MIT",
+			},
+			wantLine: fmt.Sprintf("Copyright (c) %d, Heath Stewart", year),
+		},
+		{
+			name: "apache",
+			params: map[string]string{
+				"git.name": "Heath Stewart",
+			},
+			args:     []string{"apache-2.0"},
+			wantLine: fmt.Sprintf("   Copyright %d Heath Stewart", year),
+		},
+		{
+			name: "unsupported",
+			params: map[string]string{
+				"git.name": "Heath Stewart",
+			},
+			args:    []string{"proprietary"},
+			wantErr: true,
+		},
+		{
+			name:    "no holder",
+			params:  map[string]string{},
+			args:    []string{"mit"},
+			wantErr: true,
+		},
+		{
+			name: "unspecified",
+			params: map[string]string{
+				"git.name": "Heath Stewart",
+				"license":  "MIT",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LicenseFunc(tt.license, tt.params)(tt.args...)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			if tt.wantLine == "" {
+				assert.Empty(t, got)
+				return
+			}
+			assert.Contains(t, strings.Split(got, "\n"), tt.wantLine)
+		})
+	}
+}
+
+func TestSPDXFunc(t *testing.T) {
+	t.Parallel()
+
+	got, err := SPDXFunc("mit")()
+	assert.NoError(t, err)
+	assert.Equal(t, "SPDX-License-Identifier: MIT", got)
+
+	got, err = SPDXFunc("")("apache-2.0")
+	assert.NoError(t, err)
+	assert.Equal(t, "SPDX-License-Identifier: Apache-2.0", got)
+
+	got, err = SPDXFunc("")()
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+}
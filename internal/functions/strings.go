@@ -0,0 +1,207 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package functions
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+// StringFuncs returns functions to convert identifiers and manipulate strings.
+func StringFuncs(lang language.Tag) map[string]any {
+	return map[string]any{
+		"camelcase":     CamelcaseFunc(lang),
+		"pascalcase":    PascalcaseFunc(lang),
+		"snakecase":     SnakecaseFunc(lang),
+		"kebabcase":     KebabcaseFunc(lang),
+		"screamingcase": ScreamingcaseFunc(lang),
+		"trim":          strings.TrimSpace,
+		"trimPrefix":    TrimPrefix,
+		"trimSuffix":    TrimSuffix,
+		"split":         Split,
+		"join":          Join,
+		"indent":        Indent,
+		"nindent":       Nindent,
+		"regexReplace":  RegexReplace,
+		"contains":      Contains,
+		"default":       Default,
+	}
+}
+
+func CamelcaseFunc(lang language.Tag) func(string) string {
+	lower := cases.Lower(lang)
+	title := titleWord(lang)
+	return func(s string) string {
+		words := Words(s)
+		for i, word := range words {
+			if i == 0 {
+				words[i] = lower.String(word)
+			} else {
+				words[i] = title(word)
+			}
+		}
+		return strings.Join(words, "")
+	}
+}
+
+func PascalcaseFunc(lang language.Tag) func(string) string {
+	title := titleWord(lang)
+	return func(s string) string {
+		words := Words(s)
+		for i, word := range words {
+			words[i] = title(word)
+		}
+		return strings.Join(words, "")
+	}
+}
+
+func SnakecaseFunc(lang language.Tag) func(string) string {
+	return joinWords(cases.Lower(lang), "_")
+}
+
+func KebabcaseFunc(lang language.Tag) func(string) string {
+	return joinWords(cases.Lower(lang), "-")
+}
+
+func ScreamingcaseFunc(lang language.Tag) func(string) string {
+	return joinWords(cases.Upper(lang), "_")
+}
+
+// Words splits s into words separated by any non-alphanumeric characters
+// or changes in case e.g., "HTTPServer" and "http_server" both return ["HTTP", "Server"]
+// and ["http", "server"] respectively.
+func Words(s string) []string {
+	words := make([]string, 0)
+	runes := []rune(s)
+
+	start := -1
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if start >= 0 {
+				words = append(words, string(runes[start:i]))
+				start = -1
+			}
+			continue
+		}
+
+		if start < 0 {
+			start = i
+			continue
+		}
+
+		prev := runes[i-1]
+		switch {
+		// "fooBar" splits before "B".
+		case unicode.IsUpper(r) && !unicode.IsUpper(prev):
+			fallthrough
+		// "HTTPServer" splits before "S".
+		case unicode.IsUpper(prev) && unicode.IsUpper(r) && i+1 < len(runes) && unicode.IsLower(runes[i+1]):
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+
+	if start >= 0 {
+		words = append(words, string(runes[start:]))
+	}
+
+	return words
+}
+
+func titleWord(lang language.Tag) func(string) string {
+	lower := cases.Lower(lang)
+	upper := cases.Upper(lang)
+	return func(word string) string {
+		runes := []rune(word)
+		if len(runes) == 0 {
+			return word
+		}
+		return upper.String(string(runes[0])) + lower.String(string(runes[1:]))
+	}
+}
+
+func joinWords(c cases.Caser, sep string) func(string) string {
+	return func(s string) string {
+		return c.String(strings.Join(Words(s), sep))
+	}
+}
+
+func TrimPrefix(prefix, s string) string {
+	return strings.TrimPrefix(s, prefix)
+}
+
+func TrimSuffix(suffix, s string) string {
+	return strings.TrimSuffix(s, suffix)
+}
+
+func Split(sep, s string) []string {
+	return strings.Split(s, sep)
+}
+
+func Join(sep string, values any) (string, error) {
+	switch values := values.(type) {
+	case []string:
+		return strings.Join(values, sep), nil
+	case string:
+		return values, nil
+	}
+
+	v := reflect.ValueOf(values)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return "", fmt.Errorf("cannot join %T", values)
+	}
+
+	s := make([]string, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		s[i] = fmt.Sprint(v.Index(i).Interface())
+	}
+	return strings.Join(s, sep), nil
+}
+
+func Indent(spaces int, s string) string {
+	pad := strings.Repeat(" ", spaces)
+	return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+}
+
+func Nindent(spaces int, s string) string {
+	return "\n" + Indent(spaces, s)
+}
+
+func RegexReplace(pattern, replacement, s string) (string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", err
+	}
+	return re.ReplaceAllString(s, replacement), nil
+}
+
+func Contains(substr, s string) bool {
+	return strings.Contains(s, substr)
+}
+
+func Default(defaultValue, value any) any {
+	if value == nil {
+		return defaultValue
+	}
+
+	v := reflect.ValueOf(value)
+	if v.IsZero() {
+		return defaultValue
+	}
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array:
+		if v.Len() == 0 {
+			return defaultValue
+		}
+	}
+
+	return value
+}
@@ -0,0 +1,143 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package functions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/language"
+)
+
+func TestWords(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		s    string
+		want []string
+	}{
+		{
+			name: "empty",
+			want: []string{},
+		},
+		{
+			name: "spaces",
+			s:    "  hello   world ",
+			want: []string{"hello", "world"},
+		},
+		{
+			name: "camel",
+			s:    "helloWorld",
+			want: []string{"hello", "World"},
+		},
+		{
+			name: "acronym",
+			s:    "HTTPServer",
+			want: []string{"HTTP", "Server"},
+		},
+		{
+			name: "mixed",
+			s:    "gh-template_v2 API",
+			want: []string{"gh", "template", "v2", "API"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Words(tt.s))
+		})
+	}
+}
+
+func TestCaseFuncs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		fn   func(language.Tag) func(string) string
+		s    string
+		want string
+	}{
+		{
+			name: "camelcase",
+			fn:   CamelcaseFunc,
+			s:    "gh template-HTTPServer",
+			want: "ghTemplateHttpServer",
+		},
+		{
+			name: "pascalcase",
+			fn:   PascalcaseFunc,
+			s:    "gh template-HTTPServer",
+			want: "GhTemplateHttpServer",
+		},
+		{
+			name: "snakecase",
+			fn:   SnakecaseFunc,
+			s:    "gh template-HTTPServer",
+			want: "gh_template_http_server",
+		},
+		{
+			name: "kebabcase",
+			fn:   KebabcaseFunc,
+			s:    "gh template-HTTPServer",
+			want: "gh-template-http-server",
+		},
+		{
+			name: "screamingcase",
+			fn:   ScreamingcaseFunc,
+			s:    "gh template-HTTPServer",
+			want: "GH_TEMPLATE_HTTP_SERVER",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.fn(language.English)(tt.s))
+		})
+	}
+}
+
+func TestJoin(t *testing.T) {
+	t.Parallel()
+
+	got, err := Join(", ", []string{"a", "b"})
+	assert.NoError(t, err)
+	assert.Equal(t, "a, b", got)
+
+	got, err = Join("-", []any{1, "b"})
+	assert.NoError(t, err)
+	assert.Equal(t, "1-b", got)
+
+	_, err = Join(",", 1)
+	assert.Error(t, err)
+}
+
+func TestIndent(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "  a\n  b", Indent(2, "a\nb"))
+	assert.Equal(t, "\n  a\n  b", Nindent(2, "a\nb"))
+}
+
+func TestRegexReplace(t *testing.T) {
+	t.Parallel()
+
+	got, err := RegexReplace(`(\d+)`, "<$1>", "v1.23")
+	assert.NoError(t, err)
+	assert.Equal(t, "v<1>.<23>", got)
+
+	_, err = RegexReplace(`(`, "", "")
+	assert.Error(t, err)
+}
+
+func TestDefault(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "default", Default("default", ""))
+	assert.Equal(t, "default", Default("default", nil))
+	assert.Equal(t, "default", Default("default", []string{}))
+	assert.Equal(t, "value", Default("default", "value"))
+	assert.Equal(t, 1, Default(0, 1))
+}
@@ -0,0 +1,98 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package git
+
+import (
+	"strings"
+
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-git/v5/plumbing/format/gitattributes"
+)
+
+// builtinMacros are macros git defines without an [attr] declaration.
+var builtinMacros = map[string][]string{
+	"binary": {"-diff", "-merge", "-text"},
+}
+
+// Attributes matches paths against patterns declared in .gitattributes files.
+type Attributes struct {
+	stack  []gitattributes.MatchAttribute
+	macros map[string][]gitattributes.Attribute
+}
+
+// ReadAttributes reads all .gitattributes files under root.
+func ReadAttributes(root string) (*Attributes, error) {
+	stack, err := gitattributes.ReadPatterns(osfs.New(root), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Attributes{
+		stack:  make([]gitattributes.MatchAttribute, 0, len(stack)),
+		macros: make(map[string][]gitattributes.Attribute),
+	}
+	for name, attrs := range builtinMacros {
+		m, err := gitattributes.ParseAttributesLine("[attr]"+name+" "+strings.Join(attrs, " "), nil, true)
+		if err != nil {
+			return nil, err
+		}
+		a.macros[name] = m.Attributes
+	}
+	for _, m := range stack {
+		if m.Pattern == nil {
+			a.macros[m.Name] = m.Attributes
+			continue
+		}
+		a.stack = append(a.stack, m)
+	}
+
+	return a, nil
+}
+
+// Match returns attributes for path relative to root. Like git, patterns declared later
+// and in .gitattributes files closer to path take precedence, and unspecified attributes are not returned.
+func (a *Attributes) Match(path string) map[string]gitattributes.Attribute {
+	results := make(map[string]gitattributes.Attribute)
+	if a == nil {
+		return results
+	}
+
+	parts := strings.Split(strings.ReplaceAll(path, "\\", "/"), "/")
+	for _, m := range a.stack {
+		if !m.Pattern.Match(parts) {
+			continue
+		}
+
+		for _, attr := range m.Attributes {
+			if attr.IsSet() {
+				for _, macroAttr := range a.macros[attr.Name()] {
+					a.set(results, macroAttr)
+				}
+			}
+			a.set(results, attr)
+		}
+	}
+
+	return results
+}
+
+// Binary returns whether path is marked as binary or not text e.g., "*.png binary" or "*.dat -text".
+func (a *Attributes) Binary(path string) bool {
+	attrs := a.Match(path)
+	if attr, ok := attrs["binary"]; ok && attr.IsSet() {
+		return true
+	}
+	if attr, ok := attrs["text"]; ok && attr.IsUnset() {
+		return true
+	}
+	return false
+}
+
+func (a *Attributes) set(results map[string]gitattributes.Attribute, attr gitattributes.Attribute) {
+	if attr.IsUnspecified() {
+		delete(results, attr.Name())
+		return
+	}
+	results[attr.Name()] = attr
+}
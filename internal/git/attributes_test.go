@@ -0,0 +1,49 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttributes_Binary(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "docs"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".gitattributes"), []byte(`
+*.png binary
+*.dat -text
+*.txt text
+data/*.bin !text
+`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "docs", ".gitattributes"), []byte(`
+*.dat text
+`), 0o644))
+
+	attrs, err := ReadAttributes(root)
+	require.NoError(t, err)
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{path: "logo.png", want: true},
+		{path: "assets/logo.png", want: true},
+		{path: "data.dat", want: true},
+		{path: "docs/data.dat", want: false},
+		{path: "README.txt", want: false},
+		{path: "main.go", want: false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, attrs.Binary(tt.path), tt.path)
+	}
+
+	var none *Attributes
+	assert.False(t, none.Binary("logo.png"))
+}
@@ -0,0 +1,77 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package git
+
+import (
+	"fmt"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// RenameBranch renames a local branch in the repository containing path, along with its remote-tracking branch
+// and upstream configuration, after the branch was renamed on the remote.
+func RenameBranch(path, from, to string) error {
+	repo, err := git.PlainOpenWithOptions(path, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return err
+	}
+
+	fromName := plumbing.NewBranchReferenceName(from)
+	toName := plumbing.NewBranchReferenceName(to)
+
+	ref, err := repo.Reference(fromName, false)
+	if err != nil {
+		return fmt.Errorf("failed to find branch %s: %w", from, err)
+	}
+	if err = repo.Storer.SetReference(plumbing.NewHashReference(toName, ref.Hash())); err != nil {
+		return err
+	}
+
+	if head, err := repo.Storer.Reference(plumbing.HEAD); err == nil && head.Type() == plumbing.SymbolicReference && head.Target() == fromName {
+		if err = repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, toName)); err != nil {
+			return err
+		}
+	}
+
+	if err = repo.Storer.RemoveReference(fromName); err != nil {
+		return err
+	}
+
+	cfg, err := repo.Config()
+	if err != nil {
+		return err
+	}
+
+	if branch, ok := cfg.Branches[from]; ok {
+		delete(cfg.Branches, from)
+		branch.Name = to
+		if branch.Merge == fromName {
+			branch.Merge = toName
+		}
+		cfg.Branches[to] = branch
+
+		if branch.Remote != "" {
+			remoteFrom := plumbing.NewRemoteReferenceName(branch.Remote, from)
+			remoteTo := plumbing.NewRemoteReferenceName(branch.Remote, to)
+			if remoteRef, err := repo.Reference(remoteFrom, false); err == nil {
+				if err = repo.Storer.SetReference(plumbing.NewHashReference(remoteTo, remoteRef.Hash())); err != nil {
+					return err
+				}
+				if err = repo.Storer.RemoveReference(remoteFrom); err != nil {
+					return err
+				}
+			}
+
+			remoteHead := plumbing.NewRemoteHEADReferenceName(branch.Remote)
+			if head, err := repo.Storer.Reference(remoteHead); err == nil && head.Type() == plumbing.SymbolicReference && head.Target() == remoteFrom {
+				if err = repo.Storer.SetReference(plumbing.NewSymbolicReference(remoteHead, remoteTo)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return repo.Storer.SetConfig(cfg)
+}
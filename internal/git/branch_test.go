@@ -0,0 +1,75 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenameBranch(t *testing.T) {
+	root := t.TempDir()
+	repo, err := git.PlainInit(root, false)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(root, "README.md"), []byte("# Example\n"), 0o644))
+	worktree, err := repo.Worktree()
+	require.NoError(t, err)
+	_, err = worktree.Add("README.md")
+	require.NoError(t, err)
+	hash, err := worktree.Commit("Initial commit", &git.CommitOptions{
+		Author: &object.Signature{Name: "Test User", Email: "test@domain.com", When: time.Now()},
+	})
+	require.NoError(t, err)
+
+	// Simulate a clone of the default branch "master" from origin.
+	require.NoError(t, repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, "refs/heads/master")))
+	require.NoError(t, repo.Storer.SetReference(plumbing.NewHashReference("refs/heads/master", hash)))
+	require.NoError(t, repo.Storer.SetReference(plumbing.NewHashReference("refs/remotes/origin/master", hash)))
+	require.NoError(t, repo.Storer.SetReference(plumbing.NewSymbolicReference("refs/remotes/origin/HEAD", "refs/remotes/origin/master")))
+	cfg, err := repo.Config()
+	require.NoError(t, err)
+	cfg.Remotes["origin"] = &config.RemoteConfig{Name: "origin", URLs: []string{"https://github.com/heaths/example.git"}}
+	cfg.Branches["master"] = &config.Branch{Name: "master", Remote: "origin", Merge: "refs/heads/master"}
+	require.NoError(t, repo.Storer.SetConfig(cfg))
+
+	err = RenameBranch(root, "master", "main")
+	require.NoError(t, err)
+
+	head, err := repo.Storer.Reference(plumbing.HEAD)
+	require.NoError(t, err)
+	assert.Equal(t, plumbing.ReferenceName("refs/heads/main"), head.Target())
+
+	ref, err := repo.Reference("refs/heads/main", false)
+	require.NoError(t, err)
+	assert.Equal(t, hash, ref.Hash())
+
+	_, err = repo.Reference("refs/heads/master", false)
+	assert.ErrorIs(t, err, plumbing.ErrReferenceNotFound)
+
+	ref, err = repo.Reference("refs/remotes/origin/main", false)
+	require.NoError(t, err)
+	assert.Equal(t, hash, ref.Hash())
+
+	remoteHead, err := repo.Storer.Reference("refs/remotes/origin/HEAD")
+	require.NoError(t, err)
+	assert.Equal(t, plumbing.ReferenceName("refs/remotes/origin/main"), remoteHead.Target())
+
+	cfg, err = repo.Config()
+	require.NoError(t, err)
+	assert.NotContains(t, cfg.Branches, "master")
+	if assert.Contains(t, cfg.Branches, "main") {
+		assert.Equal(t, "origin", cfg.Branches["main"].Remote)
+		assert.Equal(t, plumbing.ReferenceName("refs/heads/main"), cfg.Branches["main"].Merge)
+	}
+}
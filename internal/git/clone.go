@@ -0,0 +1,68 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package git
+
+import (
+	"errors"
+	"os"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// CloneOptions specifies how to clone a repository.
+type CloneOptions struct {
+	// Ref is the branch or tag to check out. The default branch is checked out if empty.
+	Ref string
+	// RemoteName is the name of the remote, or "origin" if empty.
+	RemoteName string
+	// Depth limits the number of commits to fetch, or all commits if 0.
+	Depth int
+	// Token is used to authenticate if not empty.
+	Token string
+}
+
+// Clone clones a repository from url into dir.
+func Clone(url, dir string, opts CloneOptions) (err error) {
+	cloneOpts := &git.CloneOptions{
+		URL:        url,
+		RemoteName: opts.RemoteName,
+		Depth:      opts.Depth,
+	}
+	if opts.Token != "" {
+		cloneOpts.Auth = &http.BasicAuth{
+			Username: "x-access-token",
+			Password: opts.Token,
+		}
+	}
+
+	if opts.Ref == "" {
+		_, err = git.PlainClone(dir, false, cloneOpts)
+		return
+	}
+
+	cloneOpts.SingleBranch = true
+	for _, ref := range []plumbing.ReferenceName{
+		plumbing.NewBranchReferenceName(opts.Ref),
+		plumbing.NewTagReferenceName(opts.Ref),
+	} {
+		cloneOpts.ReferenceName = ref
+		if _, err = git.PlainClone(dir, false, cloneOpts); err == nil || !isReferenceNotFound(err) {
+			return
+		}
+
+		// Remove any partial clone before trying the next reference.
+		if rmErr := os.RemoveAll(dir); rmErr != nil {
+			return rmErr
+		}
+	}
+
+	return
+}
+
+func isReferenceNotFound(err error) bool {
+	return errors.Is(err, git.NoMatchingRefSpecError{}) ||
+		errors.Is(err, plumbing.ErrReferenceNotFound)
+}
@@ -0,0 +1,64 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package git
+
+import (
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Commit stages all changes not ignored in the working tree containing path, including deleted files,
+// and commits them with message using author. Returns false if there was nothing to commit.
+func Commit(path, message string, author Identity) (bool, error) {
+	repo, err := git.PlainOpenWithOptions(path, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return false, err
+	}
+
+	worktree, err := worktree(repo)
+	if err != nil {
+		return false, err
+	}
+
+	status, err := worktree.Status()
+	if err != nil {
+		return false, err
+	}
+
+	changed := false
+	for name, s := range status {
+		switch s.Worktree {
+		case git.Unmodified:
+			changed = changed || s.Staging != git.Unmodified
+			continue
+		case git.Deleted:
+			_, err = worktree.Remove(name)
+		default:
+			_, err = worktree.Add(name)
+		}
+		if err != nil {
+			return false, err
+		}
+		changed = true
+	}
+
+	if !changed {
+		return false, nil
+	}
+
+	_, err = worktree.Commit(message, &git.CommitOptions{
+		Author: &object.Signature{
+			Name:  author.Name,
+			Email: author.Email,
+			When:  time.Now(),
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
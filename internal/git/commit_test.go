@@ -0,0 +1,75 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommit(t *testing.T) {
+	root := t.TempDir()
+	repo, err := git.PlainInit(root, false)
+	require.NoError(t, err)
+
+	write := func(name, content string) {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	write(".gitignore", "*.log\n")
+	write("README.md", "# {{param \"name\"}}\n")
+	write("_partials/header.md", "// header\n")
+
+	worktree, err := repo.Worktree()
+	require.NoError(t, err)
+	_, err = worktree.Add(".")
+	require.NoError(t, err)
+	_, err = worktree.Commit("Initial commit", &git.CommitOptions{
+		Author: &object.Signature{Name: "Test User", Email: "test@domain.com", When: time.Now()},
+	})
+	require.NoError(t, err)
+
+	author := Identity{Name: "Other User", Email: "other@domain.com"}
+
+	write("README.md", "# Example\n")
+	write("main.go", "package main\n")
+	write("debug.log", "ignored\n")
+	require.NoError(t, os.RemoveAll(filepath.Join(root, "_partials")))
+
+	committed, err := Commit(root, "Format template", author)
+	require.NoError(t, err)
+	assert.True(t, committed)
+
+	files, err := Status(root)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+
+	head, err := repo.Head()
+	require.NoError(t, err)
+	commit, err := repo.CommitObject(head.Hash())
+	require.NoError(t, err)
+	assert.Equal(t, "Format template", commit.Message)
+	assert.Equal(t, "Other User", commit.Author.Name)
+
+	tree, err := commit.Tree()
+	require.NoError(t, err)
+	var names []string
+	require.NoError(t, tree.Files().ForEach(func(f *object.File) error {
+		names = append(names, f.Name)
+		return nil
+	}))
+	assert.ElementsMatch(t, []string{".gitignore", "README.md", "main.go"}, names)
+
+	committed, err = Commit(root, "Nothing", author)
+	require.NoError(t, err)
+	assert.False(t, committed)
+}
@@ -4,34 +4,341 @@
 package git
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
 
+	"github.com/go-git/gcfg"
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/storage/filesystem"
 )
 
-func User() (name, email string, err error) {
+// Maximum depth of nested include directives, same as git.
+const maxIncludeDepth = 10
+
+// ErrNotRepository is returned if a path is not within a Git repository.
+var ErrNotRepository = git.ErrRepositoryNotExists
+
+// Identity is the Git user identity and the source from which each value was read.
+type Identity struct {
+	Name        string
+	NameSource  string
+	Email       string
+	EmailSource string
+}
+
+// User gets the Git author identity for the repository containing path using the same precedence as git:
+// GIT_AUTHOR_* environment variables, then author.* and user.* from local, global, and system
+// config files including any include and includeIf directives.
+func User(path string) (user Identity, err error) {
+	var gitDir, branch string
+
+	var repo *git.Repository
+	if repo, err = git.PlainOpenWithOptions(path, &git.PlainOpenOptions{DetectDotGit: true}); err == nil {
+		if storage, ok := repo.Storer.(*filesystem.Storage); ok {
+			gitDir = storage.Filesystem().Root()
+		}
+		branch, _ = branchFromRepo(repo)
+	} else if !errors.Is(err, git.ErrRepositoryNotExists) {
+		return
+	}
+
+	return userFromConfig(gitDir, branch)
+}
+
+// Branch gets the current branch of the repository containing path.
+func Branch(path string) (branch string, err error) {
+	var repo *git.Repository
+	if repo, err = git.PlainOpenWithOptions(path, &git.PlainOpenOptions{DetectDotGit: true}); err != nil {
+		return
+	}
+
+	return branchFromRepo(repo)
+}
+
+// Dir gets the absolute path to the .git directory of the repository containing path.
+func Dir(path string) (dir string, err error) {
+	var repo *git.Repository
+	if repo, err = git.PlainOpenWithOptions(path, &git.PlainOpenOptions{DetectDotGit: true}); err != nil {
+		return
+	}
+
+	storage, ok := repo.Storer.(*filesystem.Storage)
+	if !ok {
+		err = fmt.Errorf("repository is not stored on disk")
+		return
+	}
+
+	return filepath.Abs(storage.Filesystem().Root())
+}
+
+// Root gets the absolute path to the root of the working tree containing path.
+func Root(path string) (root string, err error) {
 	var repo *git.Repository
-	if repo, err = git.PlainOpenWithOptions(".", &git.PlainOpenOptions{DetectDotGit: true}); err != nil {
+	if repo, err = git.PlainOpenWithOptions(path, &git.PlainOpenOptions{DetectDotGit: true}); err != nil {
+		return
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
 		return
 	}
 
-	return userFromRepo(repo, config.GlobalScope)
+	return filepath.Abs(worktree.Filesystem.Root())
 }
 
-func userFromRepo(repo *git.Repository, scope config.Scope) (name, email string, err error) {
-	var cfg *config.Config
-	if cfg, err = repo.ConfigScoped(scope); err != nil {
+func branchFromRepo(repo *git.Repository) (branch string, err error) {
+	var head *plumbing.Reference
+	if head, err = repo.Head(); err != nil {
+		return
+	}
+
+	if !head.Name().IsBranch() {
+		err = fmt.Errorf("HEAD is detached")
 		return
 	}
 
-	if name = cfg.User.Name; name == "" {
+	branch = head.Name().Short()
+	return
+}
+
+func userFromConfig(gitDir, branch string) (user Identity, err error) {
+	r := &configReader{
+		gitDir: gitDir,
+		branch: branch,
+		values: make(map[string]configValue),
+	}
+	for _, path := range configPaths(gitDir) {
+		if err = r.read(path, 0); err != nil {
+			return
+		}
+	}
+
+	var ok bool
+	if user.Name, user.NameSource, ok = r.lookup("GIT_AUTHOR_NAME", "author.name", "user.name"); !ok {
 		err = fmt.Errorf("user.name not set")
 		return
 	}
-	if email = cfg.User.Email; email == "" {
+	if user.Email, user.EmailSource, ok = r.lookup("GIT_AUTHOR_EMAIL", "author.email", "user.email"); !ok {
 		err = fmt.Errorf("user.email not set")
 		return
 	}
 	return
 }
+
+// configPaths returns config file paths from lowest to highest precedence.
+func configPaths(gitDir string) []string {
+	paths := make([]string, 0, 4)
+
+	if !isTrue(os.Getenv("GIT_CONFIG_NOSYSTEM")) {
+		paths = append(paths, systemConfigPath())
+	}
+
+	if path := os.Getenv("GIT_CONFIG_GLOBAL"); path != "" {
+		paths = append(paths, path)
+	} else if home, err := os.UserHomeDir(); err == nil {
+		if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+			paths = append(paths, filepath.Join(xdg, "git", "config"))
+		} else {
+			paths = append(paths, filepath.Join(home, ".config", "git", "config"))
+		}
+		paths = append(paths, filepath.Join(home, ".gitconfig"))
+	}
+
+	if gitDir != "" {
+		paths = append(paths, filepath.Join(gitDir, "config"))
+	}
+
+	return paths
+}
+
+// systemConfigPath returns the path to the system config file. Like git, this is relative to the installation prefix
+// e.g., "C:\Program Files\Git\etc\gitconfig" for Git for Windows, or the same path as go-git's system scope
+// if git is installed under /usr or not found.
+func systemConfigPath() string {
+	if path := os.Getenv("GIT_CONFIG_SYSTEM"); path != "" {
+		return path
+	}
+
+	if exe, err := exec.LookPath("git"); err == nil {
+		// git is installed in a bin or cmd directory under the prefix.
+		prefix := filepath.Dir(filepath.Dir(exe))
+		if filepath.ToSlash(prefix) != "/usr" {
+			return filepath.Join(prefix, "etc", "gitconfig")
+		}
+	}
+
+	if paths, err := config.Paths(config.SystemScope); err == nil && len(paths) > 0 {
+		return paths[0]
+	}
+	return "/etc/gitconfig"
+}
+
+type configValue struct {
+	value  string
+	source string
+}
+
+type configReader struct {
+	gitDir string
+	branch string
+	values map[string]configValue
+}
+
+func (r *configReader) read(path string, depth int) error {
+	if depth > maxIncludeDepth {
+		return fmt.Errorf("exceeded maximum include depth reading %s", path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	return gcfg.ReadWithCallback(f, func(section, subsection, key, value string, blank bool) error {
+		if key == "" || blank {
+			return nil
+		}
+
+		section = strings.ToLower(section)
+		key = strings.ToLower(key)
+
+		switch {
+		case section == "include" && subsection == "" && key == "path":
+			return r.read(r.includePath(path, value), depth+1)
+		case section == "includeif" && key == "path":
+			if r.matches(path, subsection) {
+				return r.read(r.includePath(path, value), depth+1)
+			}
+		case (section == "user" || section == "author") && subsection == "" && (key == "name" || key == "email"):
+			// Later values take precedence, which is the same order git reads values.
+			r.values[section+"."+key] = configValue{value: value, source: path}
+		}
+
+		return nil
+	})
+}
+
+func (r *configReader) lookup(keys ...string) (value, source string, ok bool) {
+	for _, key := range keys {
+		if strings.HasPrefix(key, "GIT_") {
+			if value = os.Getenv(key); value != "" {
+				return value, key, true
+			}
+			continue
+		}
+
+		if v, found := r.values[key]; found && v.value != "" {
+			return v.value, v.source, true
+		}
+	}
+
+	return "", "", false
+}
+
+func (r *configReader) includePath(from, path string) string {
+	path = expandHome(path)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(from), path)
+	}
+	return path
+}
+
+func (r *configReader) matches(from, condition string) bool {
+	kind, pattern, ok := strings.Cut(condition, ":")
+	if !ok {
+		return false
+	}
+
+	switch kind {
+	case "gitdir", "gitdir/i":
+		if r.gitDir == "" {
+			return false
+		}
+
+		dir := strings.HasSuffix(pattern, "/")
+		pattern = expandHome(pattern)
+		if strings.HasPrefix(pattern, "./") {
+			pattern = filepath.Join(filepath.Dir(from), pattern[2:])
+		}
+		pattern = filepath.ToSlash(pattern)
+		if !strings.HasPrefix(pattern, "/") && !filepath.IsAbs(pattern) {
+			pattern = "**/" + pattern
+		}
+		if dir {
+			pattern = strings.TrimSuffix(pattern, "/") + "/**"
+		}
+
+		gitDir := filepath.ToSlash(r.gitDir)
+		return globMatch(pattern, gitDir, kind == "gitdir/i") || globMatch(pattern, gitDir+"/", kind == "gitdir/i")
+
+	case "onbranch":
+		if r.branch == "" {
+			return false
+		}
+
+		if strings.HasSuffix(pattern, "/") {
+			pattern += "**"
+		}
+		return globMatch(pattern, r.branch, false)
+	}
+
+	return false
+}
+
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[1:])
+		}
+	}
+	return path
+}
+
+// globMatch matches s using wildmatch patterns supported by git config conditions.
+func globMatch(pattern, s string, ignoreCase bool) bool {
+	var expr strings.Builder
+	if ignoreCase {
+		expr.WriteString("(?i)")
+	}
+	expr.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			expr.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case c == '*':
+			expr.WriteString("[^/]*")
+		case c == '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expr.WriteString("$")
+
+	matched, err := regexp.MatchString(expr.String(), s)
+	return err == nil && matched
+}
+
+func isTrue(s string) bool {
+	switch strings.ToLower(s) {
+	case "1", "true", "yes", "on":
+		return true
+	default:
+		return false
+	}
+}
@@ -4,84 +4,292 @@
 package git
 
 import (
+	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
 
-	"github.com/go-git/go-git/v5"
-	"github.com/go-git/go-git/v5/config"
-	"github.com/go-git/go-git/v5/storage/memory"
 	"github.com/stretchr/testify/assert"
 )
 
-func TestUserFromRepo(t *testing.T) {
-	t.Parallel()
-
+func TestUserFromConfig(t *testing.T) {
 	const (
 		_name  = "Test User"
 		_email = "test@domain.com"
 	)
 
-	type user struct {
-		Name  string
-		Email string
-	}
 	tests := []struct {
 		name      string
-		user      user
-		scope     config.Scope
-		wantName  bool
-		wantEmail bool
-		wantErr   bool
+		env       map[string]string
+		system    string
+		global    string
+		xdg       string
+		local     string
+		files     map[string]string
+		branch    string
+		wantName  string
+		wantEmail string
+		// Sources relative to the temp directory, or environment variable names.
+		wantNameSource  string
+		wantEmailSource string
+		wantErr         bool
 	}{
 		{
-			name: "local",
-			user: user{
-				Name:  _name,
-				Email: _email,
+			name: "global",
+			global: `[user]
+	name = Test User
+	email = test@domain.com`,
+			wantName:        _name,
+			wantEmail:       _email,
+			wantNameSource:  "home/.gitconfig",
+			wantEmailSource: "home/.gitconfig",
+		},
+		{
+			name: "xdg",
+			xdg: `[user]
+	name = Test User
+	email = test@domain.com`,
+			wantName:        _name,
+			wantEmail:       _email,
+			wantNameSource:  "home/.config/git/config",
+			wantEmailSource: "home/.config/git/config",
+		},
+		{
+			name: "system",
+			system: `[user]
+	name = Test User
+	email = test@domain.com`,
+			wantName:        _name,
+			wantEmail:       _email,
+			wantNameSource:  "gitconfig",
+			wantEmailSource: "gitconfig",
+		},
+		{
+			name: "local overrides global",
+			global: `[user]
+	name = Test User
+	email = personal@domain.com`,
+			local: `[user]
+	email = test@domain.com`,
+			wantName:        _name,
+			wantEmail:       _email,
+			wantNameSource:  "home/.gitconfig",
+			wantEmailSource: "repo/.git/config",
+		},
+		{
+			name: "author overrides user",
+			system: `[author]
+	name = Test User`,
+			local: `[user]
+	name = Other User
+	email = test@domain.com`,
+			wantName:        _name,
+			wantEmail:       _email,
+			wantNameSource:  "gitconfig",
+			wantEmailSource: "repo/.git/config",
+		},
+		{
+			name: "include",
+			global: `[user]
+	name = Test User
+	email = personal@domain.com
+[include]
+	path = .gitconfig-work`,
+			files: map[string]string{
+				"home/.gitconfig-work": `[user]
+	email = test@domain.com`,
+			},
+			wantName:        _name,
+			wantEmail:       _email,
+			wantNameSource:  "home/.gitconfig",
+			wantEmailSource: "home/.gitconfig-work",
+		},
+		{
+			name: "includeIf gitdir",
+			global: `[user]
+	name = Test User
+	email = personal@domain.com
+[includeIf "gitdir:~/../repo/"]
+	path = ~/.gitconfig-work`,
+			files: map[string]string{
+				"home/.gitconfig-work": `[user]
+	email = test@domain.com`,
+			},
+			wantName:        _name,
+			wantEmail:       _email,
+			wantNameSource:  "home/.gitconfig",
+			wantEmailSource: "home/.gitconfig-work",
+		},
+		{
+			name: "includeIf gitdir mismatch",
+			global: `[user]
+	name = Test User
+	email = test@domain.com
+[includeIf "gitdir:~/work/"]
+	path = ~/.gitconfig-work`,
+			files: map[string]string{
+				"home/.gitconfig-work": `[user]
+	email = work@domain.com`,
+			},
+			wantName:        _name,
+			wantEmail:       _email,
+			wantNameSource:  "home/.gitconfig",
+			wantEmailSource: "home/.gitconfig",
+		},
+		{
+			name: "includeIf onbranch",
+			global: `[user]
+	name = Test User
+	email = personal@domain.com
+[includeIf "onbranch:work/"]
+	path = .gitconfig-work`,
+			files: map[string]string{
+				"home/.gitconfig-work": `[user]
+	email = test@domain.com`,
 			},
-			scope:     config.GlobalScope,
-			wantName:  true,
-			wantEmail: true,
+			branch:          "work/feature",
+			wantName:        _name,
+			wantEmail:       _email,
+			wantNameSource:  "home/.gitconfig",
+			wantEmailSource: "home/.gitconfig-work",
 		},
 		{
-			name:  "global",
-			scope: config.GlobalScope,
+			name: "environment",
+			env: map[string]string{
+				"GIT_AUTHOR_NAME":  _name,
+				"GIT_AUTHOR_EMAIL": _email,
+			},
+			local: `[user]
+	name = Other User
+	email = other@domain.com`,
+			wantName:        _name,
+			wantEmail:       _email,
+			wantNameSource:  "GIT_AUTHOR_NAME",
+			wantEmailSource: "GIT_AUTHOR_EMAIL",
+		},
+		{
+			name: "committer environment",
+			env: map[string]string{
+				"GIT_AUTHOR_NAME":     _name,
+				"GIT_COMMITTER_NAME":  "Other User",
+				"GIT_COMMITTER_EMAIL": "other@domain.com",
+			},
+			local: `[user]
+	name = Local User
+	email = test@domain.com`,
+			wantName:        _name,
+			wantEmail:       _email,
+			wantNameSource:  "GIT_AUTHOR_NAME",
+			wantEmailSource: "repo/.git/config",
 		},
 		{
-			name:    "unset",
-			scope:   config.LocalScope,
+			name: "unset",
+			global: `[user]
+	name = Test User`,
 			wantErr: true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			fs := memory.NewStorage()
-			err := fs.SetConfig(&config.Config{
-				User: tt.user,
-			})
-			assert.NoError(t, err)
+			root := t.TempDir()
+			write := func(name, content string) {
+				if content == "" {
+					return
+				}
+				path := filepath.Join(root, name)
+				assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+				assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+			}
 
-			repo, err := git.Init(fs, nil)
-			assert.NoError(t, err)
+			write("gitconfig", tt.system)
+			write("home/.gitconfig", tt.global)
+			write("home/.config/git/config", tt.xdg)
+			write("repo/.git/config", tt.local)
+			for name, content := range tt.files {
+				write(name, content)
+			}
+
+			gitDir := filepath.Join(root, "repo", ".git")
+			assert.NoError(t, os.MkdirAll(gitDir, 0o755))
+
+			for _, name := range []string{
+				"GIT_AUTHOR_NAME",
+				"GIT_AUTHOR_EMAIL",
+				"GIT_COMMITTER_NAME",
+				"GIT_COMMITTER_EMAIL",
+				"GIT_CONFIG_GLOBAL",
+				"GIT_CONFIG_NOSYSTEM",
+				"XDG_CONFIG_HOME",
+			} {
+				t.Setenv(name, tt.env[name])
+			}
+			t.Setenv("HOME", filepath.Join(root, "home"))
+			t.Setenv("USERPROFILE", filepath.Join(root, "home"))
+			t.Setenv("GIT_CONFIG_SYSTEM", filepath.Join(root, "gitconfig"))
 
-			name, email, err := userFromRepo(repo, tt.scope)
+			user, err := userFromConfig(gitDir, tt.branch)
 			if tt.wantErr {
 				assert.Error(t, err)
 				return
-			} else {
-				assert.NoError(t, err)
 			}
+			assert.NoError(t, err)
 
-			if tt.wantName {
-				assert.Equal(t, _name, name)
-			} else {
-				assert.NotEqual(t, _name, name)
-			}
-			if tt.wantEmail {
-				assert.Equal(t, _email, email)
-			} else {
-				assert.NotEqual(t, _email, email)
+			source := func(s string) string {
+				if filepath.IsAbs(s) {
+					s, _ = filepath.Rel(root, s)
+				}
+				return filepath.ToSlash(s)
 			}
+
+			assert.Equal(t, tt.wantName, user.Name)
+			assert.Equal(t, tt.wantEmail, user.Email)
+			assert.Equal(t, tt.wantNameSource, source(user.NameSource))
+			assert.Equal(t, tt.wantEmailSource, source(user.EmailSource))
+		})
+	}
+}
+
+func TestSystemConfigPath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test uses a shell script as git")
+	}
+
+	root := t.TempDir()
+	bin := filepath.Join(root, "opt", "git", "bin")
+	assert.NoError(t, os.MkdirAll(bin, 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(bin, "git"), []byte("#!/bin/sh\n"), 0o755))
+
+	tests := []struct {
+		name string
+		env  string
+		path string
+		want string
+	}{
+		{
+			name: "environment",
+			env:  "/custom/gitconfig",
+			path: filepath.Join(root, "opt", "git", "bin"),
+			want: "/custom/gitconfig",
+		},
+		{
+			name: "prefix",
+			path: filepath.Join(root, "opt", "git", "bin"),
+			want: filepath.Join(root, "opt", "git", "etc", "gitconfig"),
+		},
+		{
+			name: "not found",
+			path: filepath.Join(root, "none"),
+			want: "/etc/gitconfig",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GIT_CONFIG_SYSTEM", tt.env)
+			t.Setenv("PATH", tt.path)
+
+			assert.Equal(t, tt.want, systemConfigPath())
 		})
 	}
 }
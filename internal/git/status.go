@@ -0,0 +1,155 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package git
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// FileStatus is the status of a changed file in the working tree.
+type FileStatus struct {
+	// Path is relative to the root of the working tree.
+	Path string
+	// Staging is the status code of the file in the index e.g., 'A' if added.
+	Staging byte
+	// Worktree is the status code of the file in the working tree e.g., 'M' if modified or '?' if untracked.
+	Worktree byte
+}
+
+// String returns the status in the same short format as git status --short.
+func (s FileStatus) String() string {
+	return fmt.Sprintf("%c%c %s", s.Staging, s.Worktree, s.Path)
+}
+
+// Status gets the modified, staged, and untracked files not ignored in the working tree containing path, sorted by path.
+func Status(path string) ([]FileStatus, error) {
+	repo, err := git.PlainOpenWithOptions(path, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, err
+	}
+
+	worktree, err := worktree(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := worktree.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]FileStatus, 0, len(status))
+	for path, s := range status {
+		if s.Staging == git.Unmodified && s.Worktree == git.Unmodified {
+			continue
+		}
+
+		files = append(files, FileStatus{
+			Path:     path,
+			Staging:  byte(s.Staging),
+			Worktree: byte(s.Worktree),
+		})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+
+	return files, nil
+}
+
+// Files gets the tracked files and untracked files not ignored in the working tree containing path,
+// relative to path and sorted.
+func Files(path string) ([]string, error) {
+	repo, err := git.PlainOpenWithOptions(path, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, err
+	}
+
+	worktree, err := worktree(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	// Paths in the index and status are relative to the root of the working tree.
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	root, err := filepath.Abs(worktree.Filesystem.Root())
+	if err != nil {
+		return nil, err
+	}
+	prefix, err := filepath.Rel(root, abs)
+	if err != nil {
+		return nil, err
+	}
+	prefix = filepath.ToSlash(prefix)
+
+	names := make(map[string]bool)
+	add := func(name string) {
+		if prefix == "." {
+			names[name] = true
+		} else if strings.HasPrefix(name, prefix+"/") {
+			names[name[len(prefix)+1:]] = true
+		}
+	}
+
+	index, err := repo.Storer.Index()
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range index.Entries {
+		add(entry.Name)
+	}
+
+	status, err := worktree.Status()
+	if err != nil {
+		return nil, err
+	}
+	for name, s := range status {
+		if s.Worktree == git.Untracked {
+			add(name)
+		}
+	}
+
+	files := make([]string, 0, len(names))
+	for name := range names {
+		files = append(files, filepath.FromSlash(name))
+	}
+	sort.Strings(files)
+
+	return files, nil
+}
+
+// worktree gets the working tree of repo including patterns from core.excludesFile in the global and system
+// git configuration, which go-git does not otherwise read when getting the status.
+func worktree(repo *git.Repository) (*git.Worktree, error) {
+	w, err := repo.Worktree()
+	if err != nil {
+		return nil, err
+	}
+
+	fs := osfs.New(string(filepath.Separator))
+	system, err := gitignore.LoadSystemPatterns(fs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read system excludes: %w", err)
+	}
+	global, err := gitignore.LoadGlobalPatterns(fs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read global excludes: %w", err)
+	}
+
+	// Patterns are in ascending order of priority.
+	w.Excludes = append(append(system, global...), w.Excludes...)
+
+	return w, nil
+}
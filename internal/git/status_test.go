@@ -0,0 +1,128 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatus(t *testing.T) {
+	root := t.TempDir()
+	repo, err := git.PlainInit(root, false)
+	require.NoError(t, err)
+
+	write := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(root, name), []byte(content), 0o644))
+	}
+	write(".gitignore", "*.log\n")
+	write("README.md", "# Example\n")
+	write("go.mod", "module example\n")
+
+	worktree, err := repo.Worktree()
+	require.NoError(t, err)
+	_, err = worktree.Add(".")
+	require.NoError(t, err)
+	_, err = worktree.Commit("Initial commit", &git.CommitOptions{
+		Author: &object.Signature{Name: "Test User", Email: "test@domain.com", When: time.Now()},
+	})
+	require.NoError(t, err)
+
+	files, err := Status(root)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+
+	write("README.md", "# Edited\n")
+	write("main.go", "package main\n")
+	write("debug.log", "ignored\n")
+	write("LICENSE", "MIT\n")
+	_, err = worktree.Add("LICENSE")
+	require.NoError(t, err)
+
+	files, err = Status(root)
+	require.NoError(t, err)
+	assert.Equal(t, []FileStatus{
+		{Path: "LICENSE", Staging: 'A', Worktree: ' '},
+		{Path: "README.md", Staging: ' ', Worktree: 'M'},
+		{Path: "main.go", Staging: '?', Worktree: '?'},
+	}, files)
+	assert.Equal(t, " M README.md", files[1].String())
+}
+
+func TestFiles(t *testing.T) {
+	root := t.TempDir()
+	repo, err := git.PlainInit(root, false)
+	require.NoError(t, err)
+
+	write := func(name, content string) {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	write(".gitignore", "*.log\nbin/\n")
+	write("README.md", "# Example\n")
+	write("src/main.go", "package main\n")
+
+	worktree, err := repo.Worktree()
+	require.NoError(t, err)
+	_, err = worktree.Add(".")
+	require.NoError(t, err)
+
+	write("src/util.go", "package main\n")
+	write("debug.log", "ignored\n")
+	write("bin/app", "ignored\n")
+
+	files, err := Files(root)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		".gitignore",
+		"README.md",
+		filepath.Join("src", "main.go"),
+		filepath.Join("src", "util.go"),
+	}, files)
+
+	files, err = Files(filepath.Join(root, "src"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"main.go", "util.go"}, files)
+}
+
+func TestStatus_globalExcludes(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	excludes := filepath.Join(home, "excludes")
+	require.NoError(t, os.WriteFile(excludes, []byte("*.swp\n.idea/\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".gitconfig"), []byte("[core]\n\texcludesFile = "+filepath.ToSlash(excludes)+"\n"), 0o644))
+
+	root := t.TempDir()
+	_, err := git.PlainInit(root, false)
+	require.NoError(t, err)
+
+	write := func(name, content string) {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	write("README.md", "# Example\n")
+	write(".README.md.swp", "swap\n")
+	write(".idea/workspace.xml", "<project/>\n")
+
+	files, err := Status(root)
+	require.NoError(t, err)
+	assert.Equal(t, []FileStatus{
+		{Path: "README.md", Staging: '?', Worktree: '?'},
+	}, files)
+
+	names, err := Files(root)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"README.md"}, names)
+}
@@ -0,0 +1,9 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+// Package template applies parameters to templates found under a directory.
+//
+// This package is a fork of github.com/heaths/go-template v0.7.0. Its processor and functions are internal to that module,
+// so options like WithFuncs, WithPartials, WithAttributes, and WithResolver cannot be added from this module.
+// Changes that are not specific to gh-template should also be proposed upstream so this fork can be removed.
+package template
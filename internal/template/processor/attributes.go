@@ -0,0 +1,22 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package processor
+
+// Attributes describe how a file should be processed.
+type Attributes struct {
+	Binary bool // Whether the file is binary and should not be processed.
+	Skip   bool // Whether the file should not be processed as a template.
+
+	LeftDelim  string // Left delimiter to use instead of the default e.g., "<%".
+	RightDelim string // Right delimiter to use instead of the default e.g., "%>".
+}
+
+// attributes returns the attributes of a file path relative to the root, if any.
+func (p *Processor) attributes(path string) Attributes {
+	if p.Attributes == nil {
+		return Attributes{}
+	}
+
+	return p.Attributes(path)
+}
@@ -0,0 +1,45 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package processor
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+)
+
+// sniffLen is the number of leading bytes searched for a NUL byte, same as git.
+const sniffLen = 8000
+
+// binaryExtensions are file extensions of common binary files in template repositories.
+var binaryExtensions = map[string]bool{
+	// Images
+	".bmp": true, ".gif": true, ".ico": true, ".jpeg": true, ".jpg": true, ".png": true, ".psd": true, ".tif": true, ".tiff": true, ".webp": true,
+	// Fonts
+	".eot": true, ".otf": true, ".ttf": true, ".woff": true, ".woff2": true,
+	// Archives
+	".7z": true, ".bz2": true, ".gz": true, ".jar": true, ".nupkg": true, ".rar": true, ".tar": true, ".tgz": true, ".xz": true, ".zip": true,
+	// Executables and libraries
+	".a": true, ".class": true, ".dll": true, ".dylib": true, ".exe": true, ".o": true, ".obj": true, ".pdb": true, ".so": true, ".wasm": true,
+	// Documents and media
+	".docx": true, ".mp3": true, ".mp4": true, ".pdf": true, ".pptx": true, ".wav": true, ".xlsx": true,
+}
+
+// isBinary returns whether a file is binary based on its attributes or extension.
+func isBinary(path string, attrs Attributes) bool {
+	if attrs.Binary {
+		return true
+	}
+
+	return binaryExtensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// IsBinaryContent returns whether content contains a NUL byte within the first sniffLen bytes.
+func IsBinaryContent(content []byte) bool {
+	if len(content) > sniffLen {
+		content = content[:sniffLen]
+	}
+
+	return bytes.IndexByte(content, 0) >= 0
+}
@@ -0,0 +1,91 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package processor
+
+import (
+	"bytes"
+	"text/template/parse"
+)
+
+var (
+	utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+	crlf    = []byte("\r\n")
+	lf      = []byte("\n")
+
+	// Line endings in literal template text are replaced with markers while rendering so they are restored unchanged.
+	// Templates never contain NUL bytes since they would be binary.
+	literalCRLF = []byte("\x00CRLF\x00")
+	literalLF   = []byte("\x00LF\x00")
+)
+
+// fileFormat describes the byte order mark and line endings of a template preserved when rendering.
+type fileFormat struct {
+	bom bool   // Whether the file starts with a UTF-8 byte order mark.
+	eol []byte // The predominant line ending, or nil if the file has no line endings.
+}
+
+// detectFormat returns the format of content and the content without any byte order mark.
+func detectFormat(content []byte) (fileFormat, []byte) {
+	var f fileFormat
+	if bytes.HasPrefix(content, utf8BOM) {
+		f.bom = true
+		content = content[len(utf8BOM):]
+	}
+
+	crlfCount := bytes.Count(content, crlf)
+	lfCount := bytes.Count(content, lf) - crlfCount
+	switch {
+	case crlfCount > lfCount:
+		f.eol = crlf
+	case lfCount > 0:
+		f.eol = lf
+	}
+
+	return f, content
+}
+
+// markLineEndings replaces line endings in literal text of the template tree with markers
+// so that apply only normalizes line endings from parameters or partials.
+func markLineEndings(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			markLineEndings(child)
+		}
+	case *parse.TextNode:
+		text := bytes.ReplaceAll(n.Text, crlf, literalCRLF)
+		n.Text = bytes.ReplaceAll(text, lf, literalLF)
+	case *parse.IfNode:
+		markLineEndings(n.List)
+		markLineEndings(n.ElseList)
+	case *parse.RangeNode:
+		markLineEndings(n.List)
+		markLineEndings(n.ElseList)
+	case *parse.WithNode:
+		markLineEndings(n.List)
+		markLineEndings(n.ElseList)
+	}
+}
+
+// apply normalizes line endings in rendered content that came from parameters or partials,
+// restores line endings in literal text marked by markLineEndings, and restores any byte order mark.
+func (f fileFormat) apply(content []byte) []byte {
+	if f.eol != nil {
+		content = bytes.ReplaceAll(content, crlf, lf)
+		if bytes.Equal(f.eol, crlf) {
+			content = bytes.ReplaceAll(content, lf, crlf)
+		}
+	}
+	content = bytes.ReplaceAll(content, literalCRLF, crlf)
+	content = bytes.ReplaceAll(content, literalLF, lf)
+
+	if f.bom {
+		content = append(append(make([]byte, 0, len(utf8BOM)+len(content)), utf8BOM...), content...)
+	}
+
+	return content
+}
@@ -0,0 +1,557 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package processor
+
+// cspell:ignore mattn isatty
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/heaths/gh-template/internal/functions"
+	"github.com/mattn/go-isatty"
+	"github.com/spf13/afero"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// DefaultPartials is the default directory containing named templates.
+const DefaultPartials = "_partials"
+
+type Processor struct {
+	Stderr io.Writer // The writer on which users are prompted.
+	Stdin  io.Reader // The reader from which user input is read.
+	IsTTY  bool      // Whether Stderr is a terminal.
+
+	LeftDelim  string   // Left delimiter e.g., "{{".
+	RightDelim string   // Right delimiter e.g., "}}".
+	Exclusions []string // Directories and files to exclude.
+	Partials   string   // Directory of named templates relative to the root e.g., "_partials".
+
+	Funcs template.FuncMap // Additional functions to register.
+
+	Resolve func(name string) (string, bool) // Optional resolver for parameters not passed before prompting.
+
+	Workers int // Maximum number of files rendered concurrently, or GOMAXPROCS if 0.
+
+	Attributes func(path string) Attributes // Optional attributes of a file path relative to the root.
+
+	Language *language.Tag     // The language used in some functions.
+	collator *collate.Collator // The collator used to sort and search for strings.
+
+	Log     *log.Logger // Optional logger for pertinent information.
+	Verbose bool        // Whether to log verbose information.
+
+	srcFS afero.Fs // The file system for reading templates.
+	dstFS afero.Fs // The file system for writing templates.
+
+	errors int // Number of errors logged (as warning logs).
+}
+
+func (p *Processor) Initialize() {
+	if p.Stderr == nil {
+		p.Stderr = os.Stderr
+		p.IsTTY = isatty.IsTerminal(os.Stderr.Fd())
+	}
+
+	if p.Stdin == nil {
+		p.Stdin = os.Stdin
+	}
+
+	if p.Language == nil {
+		p.Language = &language.English
+	}
+
+	if p.Partials == "" {
+		p.Partials = DefaultPartials
+	}
+	p.Partials = normalizePath(p.Partials)
+
+	p.collator = collate.New(*p.Language, collate.IgnoreCase)
+	p.normalizeExclusions()
+
+	if p.srcFS == nil {
+		p.srcFS = afero.NewOsFs()
+	}
+
+	if p.dstFS == nil {
+		p.dstFS = p.srcFS
+	}
+}
+
+func (p *Processor) Execute(root string, params map[string]string) error {
+	param := functions.ParamFunc(p.Stdin, p.Stderr, p.IsTTY, params)
+	if p.Resolve != nil {
+		param = functions.ResolveFunc(param, params, p.Resolve)
+	}
+
+	funcs := template.FuncMap{
+		"param":      param,
+		"lowercase":  functions.LowercaseFunc(*p.Language),
+		"titlecase":  functions.TitlecaseFunc(*p.Language),
+		"uppercase":  functions.UppercaseFunc(*p.Language),
+		"pluralize":  functions.PluralizeFunc,
+		"replace":    functions.Replace,
+		"date":       functions.DateFunc,
+		"true":       func() bool { return true },
+		"false":      func() bool { return false },
+		"deleteFile": func(...string) string { return "" },
+	}
+	for name, fn := range p.Funcs {
+		funcs[name] = fn
+	}
+
+	// Paths are always relative to the root so exclusions and deleted files resolve correctly.
+	srcFS, dstFS := p.srcFS, p.dstFS
+	if root != "" && root != "." {
+		srcFS = afero.NewBasePathFs(srcFS, root)
+		dstFS = afero.NewBasePathFs(dstFS, root)
+	}
+
+	// cspell:ignore IOFS
+	dir := afero.NewIOFS(srcFS)
+
+	partials, partialFiles, err := p.parsePartials(dir, funcs)
+	if err != nil {
+		return err
+	}
+
+	// Parse all templates first in a deterministic order.
+	jobs, err := p.parse(dir, partials, partialFiles)
+	if err != nil {
+		return err
+	}
+
+	// Resolve parameters by executing templates that call param in the same order, so users are prompted
+	// in the same order as before and only once for each parameter.
+	for _, j := range jobs {
+		if !j.params {
+			continue
+		}
+
+		if err = j.t.Execute(io.Discard, nil); err != nil {
+			p.logWarning("failed to process %q: %v\n", j.path, err)
+			j.failed = true
+		}
+	}
+
+	// All parameters are now resolved, so render templates concurrently without prompting.
+	p.render(jobs, dstFS, functions.ParamFunc(nil, nil, false, params))
+
+	// Keep track of files to delete until we're finished;
+	// otherwise, not all files to delete may yet exist in the destination FS.
+	for _, j := range jobs {
+		if j.err != nil {
+			p.logWarning("%v\n", j.err)
+			continue
+		}
+
+		for _, fileToDelete := range j.filesToDelete {
+			p.logVerbose("deleting %q", fileToDelete)
+			if err = dstFS.Remove(fileToDelete); err != nil {
+				p.logWarning("failed to delete %q: %v\n", fileToDelete, err)
+			}
+		}
+	}
+
+	if p.errors == 0 {
+		p.deletePartials(dstFS, partialFiles)
+	}
+
+	if p.errors == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("failed to process %s", functions.Pluralize(p.errors, "template"))
+}
+
+// job is a template to render.
+type job struct {
+	path   string
+	t      *template.Template
+	format fileFormat
+	mode   fs.FileMode
+
+	params bool // Whether the template calls param.
+	failed bool // Whether the template failed to resolve parameters.
+
+	err           error    // The error rendering the template, if any.
+	filesToDelete []string // Files the template requested be deleted.
+}
+
+// parse walks dir and parses all files that are templates, except for files already parsed as partials.
+func (p *Processor) parse(dir fs.FS, partials *template.Template, partialFiles map[string]bool) ([]*job, error) {
+	jobs := make([]*job, 0)
+	err := fs.WalkDir(dir, ".", func(path string, d fs.DirEntry, err error) (_ error) {
+		// TODO: Bubble up errors to channel but carry on with as many files as possible.
+		if err != nil {
+			p.logWarning("failed to walk %q: %v\n", path, err)
+			return
+		}
+
+		switch {
+		// Always ignore repos to avoid catastrophe.
+		case path == ".git" || path == ".hg":
+			p.logVerbose("skipping %q", path)
+			return fs.SkipDir
+		case partialFiles[path]:
+			return
+		case p.exclude(path):
+			p.logVerbose("skipping %q", path)
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return
+		case d.IsDir():
+			return
+		}
+
+		attrs := p.attributes(path)
+		switch {
+		case attrs.Skip:
+			p.logVerbose("skipping %q", path)
+			return
+		case isBinary(path, attrs):
+			p.logVerbose("skipping binary %q", path)
+			return
+		}
+		p.logVerbose("processing %q", path)
+
+		t, err := partials.Clone()
+		if err != nil {
+			p.logWarning("failed to parse %q: %v\n", path, err)
+			return
+		}
+
+		var content []byte
+		content, err = fs.ReadFile(dir, path)
+		if err != nil {
+			p.logWarning("failed to read %q: %v\n", path, err)
+			return
+		}
+
+		if IsBinaryContent(content) {
+			p.logVerbose("skipping binary %q", path)
+			return
+		}
+
+		t = t.New(d.Name())
+		if attrs.LeftDelim != "" || attrs.RightDelim != "" {
+			if attrs.LeftDelim == "" || attrs.RightDelim == "" {
+				p.logWarning("failed to parse %q: both left and right delimiters are required\n", path)
+				return
+			}
+			t = t.Delims(attrs.LeftDelim, attrs.RightDelim)
+		}
+
+		format, content := detectFormat(content)
+		t, err = t.Parse(string(content))
+		if err != nil {
+			p.logWarning("failed to parse %q: %v\n", path, err)
+			return
+		}
+
+		if !isTemplate(t) {
+			p.logVerbose("skipping non-template %q", path)
+			return
+		}
+
+		// Only the file's own tree is marked, since partials are shared by all files.
+		markLineEndings(t.Tree.Root)
+
+		var info fs.FileInfo
+		if info, err = d.Info(); err != nil {
+			p.logWarning("failed to read %q: %v\n", path, err)
+			return
+		}
+
+		jobs = append(jobs, &job{
+			path:   path,
+			t:      t,
+			format: format,
+			mode:   info.Mode().Perm(),
+			params: callsFunc(t, t.Tree, "param", make(map[string]bool)),
+		})
+
+		return
+	})
+
+	return jobs, err
+}
+
+// render executes templates concurrently using up to p.Workers goroutines.
+func (p *Processor) render(jobs []*job, dstFS afero.Fs, param any) {
+	workers := p.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	queue := make(chan *job)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range queue {
+				j.err = p.renderJob(j, dstFS, param)
+			}
+		}()
+	}
+
+	for _, j := range jobs {
+		if !j.failed {
+			queue <- j
+		}
+	}
+	close(queue)
+	wg.Wait()
+}
+
+func (p *Processor) renderJob(j *job, dstFS afero.Fs, param any) error {
+	// Each template is a separate clone, so functions with per-file state can be replaced safely.
+	current := j.path
+	var deleteFiles bool
+	var filesToDelete []string
+	j.t.Funcs(template.FuncMap{
+		"param":      param,
+		"deleteFile": functions.DeleteFunc(&current, &deleteFiles, &filesToDelete),
+	})
+
+	var buf bytes.Buffer
+	if err := j.t.Execute(&buf, nil); err != nil {
+		return fmt.Errorf("failed to process %q: %v", j.path, err)
+	}
+
+	// Preserve the original byte order mark, line endings, and permissions e.g., executable scripts.
+	if err := afero.WriteFile(dstFS, j.path, j.format.apply(buf.Bytes()), j.mode); err != nil {
+		return fmt.Errorf("failed to write output %q: %v", j.path, err)
+	}
+	if err := dstFS.Chmod(j.path, j.mode); err != nil {
+		return fmt.Errorf("failed to write output %q: %v", j.path, err)
+	}
+
+	if deleteFiles {
+		j.filesToDelete = filesToDelete
+	}
+
+	return nil
+}
+
+// parsePartials parses text files in the partials directory as named templates
+// with names relative to the partials directory and without file extensions e.g., "go/header".
+// Excluded, skipped, and binary files are not partials and are processed like any other file.
+// The paths of files parsed as partials are returned.
+func (p *Processor) parsePartials(dir fs.FS, funcs template.FuncMap) (*template.Template, map[string]bool, error) {
+	t := template.New("").Funcs(funcs)
+	if p.LeftDelim != "" && p.RightDelim != "" {
+		t = t.Delims(p.LeftDelim, p.RightDelim)
+	}
+
+	partials := p.Partials
+	files := make(map[string]bool)
+	err := fs.WalkDir(dir, partials, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && path == partials {
+				return fs.SkipDir
+			}
+			return err
+		}
+
+		if p.exclude(path) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		if attrs := p.attributes(path); attrs.Skip || isBinary(path, attrs) {
+			return nil
+		}
+
+		name := strings.TrimPrefix(path, partials+"/")
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+
+		var content []byte
+		if content, err = fs.ReadFile(dir, path); err != nil {
+			return err
+		}
+
+		if IsBinaryContent(content) {
+			return nil
+		}
+
+		// Line endings are normalized when rendering, but a byte order mark would be inserted mid-file.
+		content = bytes.TrimPrefix(content, utf8BOM)
+
+		p.logVerbose("parsing partial %q as %q", path, name)
+		if _, err = t.New(name).Parse(string(content)); err != nil {
+			return fmt.Errorf("failed to parse partial %q: %w", path, err)
+		}
+		files[path] = true
+
+		return nil
+	})
+
+	return t, files, err
+}
+
+// deletePartials deletes files parsed as partials and any directories under the partials directory left empty.
+func (p *Processor) deletePartials(dstFS afero.Fs, files map[string]bool) {
+	if len(files) == 0 {
+		return
+	}
+
+	dirs := make(map[string]bool)
+	for path := range files {
+		p.logVerbose("deleting %q", path)
+		if err := dstFS.RemoveAll(path); err != nil {
+			p.logWarning("failed to delete %q: %v\n", path, err)
+			continue
+		}
+
+		for dir := filepath.ToSlash(filepath.Dir(path)); dir == p.Partials || strings.HasPrefix(dir, p.Partials+"/"); dir = filepath.ToSlash(filepath.Dir(dir)) {
+			dirs[dir] = true
+		}
+	}
+
+	// Delete the deepest directories first, leaving any that still contain other files.
+	sorted := maps.Keys(dirs)
+	sort.Sort(sort.Reverse(sort.StringSlice(sorted)))
+	for _, dir := range sorted {
+		if entries, err := afero.ReadDir(dstFS, dir); err == nil && len(entries) == 0 {
+			p.logVerbose("deleting %q", dir)
+			if err = dstFS.Remove(dir); err != nil {
+				p.logWarning("failed to delete %q: %v\n", dir, err)
+			}
+		}
+	}
+}
+
+func (p *Processor) logVerbose(format string, v ...any) {
+	if p.Verbose && p.Log != nil {
+		p.Log.Printf(format, v...)
+	}
+}
+
+func (p *Processor) logWarning(format string, v ...any) {
+	p.errors++
+	if p.Log != nil {
+		p.Log.Printf(format, v...)
+	}
+}
+
+func (p *Processor) exclude(s string) bool {
+	_, found := slices.BinarySearchFunc(p.Exclusions, s, p.collator.CompareString)
+	return found
+}
+
+func (p *Processor) normalizeExclusions() {
+	// Copy exclusions so the caller's slice is not modified.
+	src := slices.Clone(p.Exclusions)
+	p.Exclusions = src
+	for i, s := range src {
+		src[i] = normalizePath(s)
+	}
+
+	p.collator.SortStrings(src)
+}
+
+func normalizePath(s string) string {
+	s = strings.ReplaceAll(s, "\\", "/")
+	s = strings.TrimRightFunc(s, func(r rune) bool {
+		return r == '/'
+	})
+
+	if strings.HasPrefix(s, "/") {
+		return s[1:]
+	} else if strings.HasPrefix(s, "./") {
+		return s[2:]
+	}
+	return s
+}
+
+func isTemplate(t *template.Template) bool {
+	for _, node := range t.Root.Nodes {
+		if node.Type() != parse.NodeText {
+			return true
+		}
+	}
+	return false
+}
+
+// callsFunc returns whether tree or any template it executes calls the function name.
+func callsFunc(t *template.Template, tree *parse.Tree, name string, visited map[string]bool) bool {
+	if tree == nil || visited[tree.ParseName+"\x00"+tree.Name] {
+		return false
+	}
+	visited[tree.ParseName+"\x00"+tree.Name] = true
+
+	var walk func(node parse.Node) bool
+	walk = func(node parse.Node) bool {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return false
+			}
+			for _, child := range n.Nodes {
+				if walk(child) {
+					return true
+				}
+			}
+		case *parse.ActionNode:
+			return walk(n.Pipe)
+		case *parse.PipeNode:
+			if n == nil {
+				return false
+			}
+			for _, cmd := range n.Cmds {
+				if walk(cmd) {
+					return true
+				}
+			}
+		case *parse.CommandNode:
+			for _, arg := range n.Args {
+				if walk(arg) {
+					return true
+				}
+			}
+		case *parse.ChainNode:
+			return walk(n.Node)
+		case *parse.IdentifierNode:
+			return n.Ident == name
+		case *parse.IfNode:
+			return walk(n.Pipe) || walk(n.List) || walk(n.ElseList)
+		case *parse.RangeNode:
+			return walk(n.Pipe) || walk(n.List) || walk(n.ElseList)
+		case *parse.WithNode:
+			return walk(n.Pipe) || walk(n.List) || walk(n.ElseList)
+		case *parse.TemplateNode:
+			if walk(n.Pipe) {
+				return true
+			}
+			if named := t.Lookup(n.Name); named != nil {
+				return callsFunc(t, named.Tree, name, visited)
+			}
+		}
+		return false
+	}
+
+	return walk(tree.Root)
+}
@@ -0,0 +1,677 @@
+// Copyright 2022 Heath Stewart.
+// Licensed under the MIT License. See LICENSE.txt in the project root for license information.
+
+package processor
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"text/template"
+	"time"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/heaths/go-console"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+const (
+	content_a = `# {{param "name" "" "What is the project name?" | titlecase}}
+
+Project "{{param "name" | titlecase}}" is an example of template repository {{param "github.owner"}}/{{param "github.repo"}}.
+
+Copyright {{date.Local.Year}} {{param "git.name"}} under the [MIT](LICENSE.txt) license.
+`
+
+	content_a_alt = `# <%param "name" "" "What is the project name?" | titlecase%>
+
+Project "<%param "name" | titlecase%>" is an example of template repository <%param "github.owner"%>/<%param "github.repo"%>.
+
+Copyright <%date.Local.Year%> <%param "git.name"%> under the [MIT](LICENSE.txt) license.
+`
+)
+
+// cspell:ignore Docf
+func TestProcessor_Execute(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		leftDelim  string
+		rightDelim string
+		content    string
+	}{
+		{
+			name:    "defaults",
+			content: content_a,
+		},
+		{
+			name:       "alternate delims",
+			leftDelim:  "<%",
+			rightDelim: "%>",
+			content:    content_a_alt,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			con := console.Fake(
+				console.WithStdin(bytes.NewBufferString("template\n")),
+				console.WithStderrTTY(true),
+			)
+
+			srcFS := afero.NewMemMapFs()
+			require.NoError(t, srcFS.Mkdir(".git", 0755))
+			require.NoError(t, afero.WriteFile(srcFS, ".git/index", []byte("Head: main"), 0644))
+			require.NoError(t, srcFS.Mkdir("build", 0755))
+			require.NoError(t, afero.WriteFile(srcFS, "build/dat", []byte{00, 01, 02, 03}, 0644))
+			require.NoError(t, srcFS.Mkdir("testdata", 0755))
+			require.NoError(t, afero.WriteFile(srcFS, "testdata/a.md", []byte(tt.content), 0644))
+			require.NoError(t, afero.WriteFile(srcFS, "testdata/b.md", []byte("not a template"), 0644))
+
+			dstFS := afero.NewMemMapFs()
+
+			proc := Processor{
+				Stderr: con.Stderr(),
+				Stdin:  con.Stdin(),
+				IsTTY:  con.IsStderrTTY(),
+
+				LeftDelim:  tt.leftDelim,
+				RightDelim: tt.rightDelim,
+				Exclusions: []string{"Build/"},
+
+				srcFS: srcFS,
+				dstFS: afero.NewCopyOnWriteFs(srcFS, dstFS),
+			}
+			proc.Initialize()
+
+			params := map[string]string{
+				"git.name":     "Heath Stewart",
+				"github.owner": "heaths",
+				"github.repo":  "template-golang",
+			}
+			err = proc.Execute(".", params)
+			assert.NoError(t, err, "failed to process template")
+
+			_, err = dstFS.Stat(".git")
+			assert.Error(t, err)
+
+			_, err = dstFS.Stat("build")
+			assert.Error(t, err)
+
+			_, err = dstFS.Stat("testdata/b.md")
+			assert.Error(t, err)
+
+			const path = "testdata/a.md"
+			file, err := dstFS.Open(path)
+			require.NoError(t, err, "failed to open %q", path)
+
+			got, err := io.ReadAll(file)
+			require.NoError(t, err, "failed to read %q", path)
+
+			// There's a small but acceptable window where the year could be different due to TZ offset.
+			want := heredoc.Docf(`
+				# Template
+
+				Project "Template" is an example of template repository heaths/template-golang.
+
+				Copyright %s Heath Stewart under the [MIT](LICENSE.txt) license.
+				`, strconv.FormatInt(int64(time.Now().UTC().Year()), 10))
+
+			assert.Equal(t, want, string(got))
+		})
+	}
+}
+
+func TestProcessor_Execute_delete(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		release bool
+	}{
+		{
+			name:    "release",
+			release: true,
+		},
+		{
+			name: "no release",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			con := console.Fake(
+				console.WithStdin(bytes.NewBufferString("template\n")),
+				console.WithStderrTTY(true),
+			)
+
+			const path = ".github/workflows/release.yml"
+			srcFS := afero.NewMemMapFs()
+			require.NoError(t, srcFS.Mkdir(".git", 0755))
+			require.NoError(t, afero.WriteFile(srcFS, ".git/index", []byte("Head: main"), 0644))
+			require.NoError(t, srcFS.MkdirAll(".github/workflows", 0755))
+			require.NoError(t, afero.WriteFile(srcFS, path, []byte("{{if not (param \"release\" true \"Do you need release pipelines?\")}}{{deleteFile}}{{deleteFile \"CHANGELOG.md\"}}{{end -}}\nname: release"), 0644))
+			require.NoError(t, afero.WriteFile(srcFS, "CHANGELOG.md", []byte("# Changes"), 0644))
+
+			// Use the same FS to check deleted files.
+			dstFS := srcFS
+
+			proc := Processor{
+				Stderr: con.Stderr(),
+				Stdin:  con.Stdin(),
+				IsTTY:  con.IsStderrTTY(),
+
+				srcFS: srcFS,
+				dstFS: dstFS,
+			}
+			proc.Initialize()
+
+			params := map[string]string{
+				"git.name":     "Heath Stewart",
+				"github.owner": "heaths",
+				"github.repo":  "template-golang",
+				"release":      strconv.FormatBool(tt.release),
+			}
+			err = proc.Execute(".", params)
+			assert.NoError(t, err, "failed to process template")
+
+			_, err = dstFS.Stat(".git")
+			assert.NoError(t, err)
+
+			file, err := dstFS.Open(path)
+			if tt.release {
+				require.NoError(t, err, "failed to open %q, path")
+
+				got, err := io.ReadAll(file)
+				require.NoError(t, err, "failed to read %q", path)
+
+				assert.Equal(t, "name: release", string(got))
+
+				_, err = dstFS.Stat("CHANGELOG.md")
+				assert.NoError(t, err, "CHANGELOG.md should exist")
+			} else {
+				assert.Error(t, err, "%q should not exist", path)
+
+				_, err = dstFS.Stat("CHANGELOG.md")
+				assert.Error(t, err, "CHANGELOG.md should not exist")
+			}
+		})
+	}
+}
+
+func TestIsTemplate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		template string
+		want     bool
+	}{
+		{
+			name:     "template",
+			template: `Hello, {{"world"}}!`,
+			want:     true,
+		},
+		{
+			name:     "not template",
+			template: "Hello, world!",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sut, err := template.New(tt.name).Parse(tt.template)
+			assert.NoError(t, err)
+
+			got := isTemplate(sut)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestProcessor_exclude(t *testing.T) {
+	t.Parallel()
+
+	src := []string{
+		"/testdata/B",
+		"./testdata/a",
+		"build\\c",
+		"Dist/",
+	}
+
+	p := Processor{
+		Exclusions: src,
+		collator:   collate.New(language.English, collate.IgnoreCase),
+	}
+
+	p.normalizeExclusions()
+	assert.True(t, p.exclude("testdata/b"))
+}
+
+func TestProcessor_normalizeExclusions(t *testing.T) {
+	t.Parallel()
+
+	src := []string{
+		"/testdata/B",
+		"./testdata/a",
+		"build\\c",
+		"Dist/",
+	}
+
+	dst := []string{
+		"build/c",
+		"Dist",
+		"testdata/a",
+		"testdata/B",
+	}
+
+	p := Processor{
+		Exclusions: src,
+		collator:   collate.New(language.English, collate.IgnoreCase),
+	}
+
+	p.normalizeExclusions()
+	assert.Equal(t, dst, p.Exclusions)
+	assert.Equal(t, "/testdata/B", src[0], "caller's exclusions should not be modified")
+}
+
+func TestProcessor_Execute_partials(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		partials string
+		dir      string
+	}{
+		{
+			name: "default",
+			dir:  "_partials",
+		},
+		{
+			name:     "custom",
+			partials: "./templates/",
+			dir:      "templates",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			require.NoError(t, fs.MkdirAll(tt.dir+"/go", 0755))
+			require.NoError(t, afero.WriteFile(fs, tt.dir+"/header.md", []byte(`# {{param "github.repo"}}`), 0644))
+			require.NoError(t, afero.WriteFile(fs, tt.dir+"/go/header.txt", []byte(`// Copyright {{param "git.name"}}`), 0644))
+			require.NoError(t, afero.WriteFile(fs, "README.md", []byte("{{template \"header\" .}}\n\nExample"), 0644))
+			require.NoError(t, afero.WriteFile(fs, "main.go", []byte("{{template \"go/header\" .}}\npackage main"), 0644))
+
+			proc := Processor{
+				Partials: tt.partials,
+
+				srcFS: fs,
+				dstFS: fs,
+			}
+			proc.Initialize()
+
+			params := map[string]string{
+				"git.name":    "Heath Stewart",
+				"github.repo": "template-golang",
+			}
+			err := proc.Execute(".", params)
+			require.NoError(t, err)
+
+			got, err := afero.ReadFile(fs, "README.md")
+			require.NoError(t, err)
+			assert.Equal(t, "# template-golang\n\nExample", string(got))
+
+			got, err = afero.ReadFile(fs, "main.go")
+			require.NoError(t, err)
+			assert.Equal(t, "// Copyright Heath Stewart\npackage main", string(got))
+
+			_, err = fs.Stat(tt.dir)
+			assert.Error(t, err, "%q should not exist", tt.dir)
+		})
+	}
+}
+
+func TestProcessor_Execute_partialsUnrelated(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, fs.MkdirAll("_partials/images", 0755))
+	require.NoError(t, afero.WriteFile(fs, "_partials/header.md", []byte(`# {{param "github.repo"}}`), 0644))
+	require.NoError(t, afero.WriteFile(fs, "_partials/images/logo.png", []byte("\x89PNG\x00"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "_partials/build.sh", []byte(`echo {{not a partial`), 0644))
+	require.NoError(t, afero.WriteFile(fs, "README.md", []byte(`{{template "header" .}}`), 0644))
+
+	proc := Processor{
+		Attributes: func(path string) Attributes {
+			return Attributes{
+				Skip: path == "_partials/build.sh",
+			}
+		},
+
+		srcFS: fs,
+		dstFS: fs,
+	}
+	proc.Initialize()
+
+	err := proc.Execute(".", map[string]string{"github.repo": "template-golang"})
+	require.NoError(t, err)
+
+	got, err := afero.ReadFile(fs, "README.md")
+	require.NoError(t, err)
+	assert.Equal(t, "# template-golang", string(got))
+
+	_, err = fs.Stat("_partials/header.md")
+	assert.Error(t, err, "partial should be deleted")
+
+	for _, path := range []string{"_partials/images/logo.png", "_partials/build.sh"} {
+		_, err = fs.Stat(path)
+		assert.NoError(t, err, "%q should not be deleted", path)
+	}
+}
+
+func TestProcessor_Execute_binary(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "README.md", []byte(`# {{param "github.repo"}}`), 0644))
+	require.NoError(t, afero.WriteFile(fs, "logo.PNG", []byte(`{{not a template`), 0644))
+	require.NoError(t, afero.WriteFile(fs, "data.bin", []byte("\x00{{not a template"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "asset.dat", []byte(`{{not a template`), 0644))
+
+	var buf bytes.Buffer
+	proc := Processor{
+		Attributes: func(path string) Attributes {
+			return Attributes{
+				Binary: path == "asset.dat",
+			}
+		},
+		Log:     log.New(&buf, "", 0),
+		Verbose: true,
+
+		srcFS: fs,
+		dstFS: fs,
+	}
+	proc.Initialize()
+
+	err := proc.Execute(".", map[string]string{"github.repo": "template-golang"})
+	require.NoError(t, err)
+
+	got, err := afero.ReadFile(fs, "README.md")
+	require.NoError(t, err)
+	assert.Equal(t, "# template-golang", string(got))
+
+	for _, path := range []string{"logo.PNG", "data.bin", "asset.dat"} {
+		assert.Contains(t, buf.String(), fmt.Sprintf("skipping binary %q", path))
+	}
+}
+
+func TestIsBinaryContent(t *testing.T) {
+	t.Parallel()
+
+	assert.False(t, IsBinaryContent([]byte("text")))
+	assert.True(t, IsBinaryContent([]byte("te\x00xt")))
+	assert.False(t, IsBinaryContent(append(bytes.Repeat([]byte{'a'}, sniffLen), 0)))
+}
+
+func TestProcessor_Execute_attributes(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "README.md", []byte(`# {{param "github.repo"}}`), 0644))
+	require.NoError(t, afero.WriteFile(fs, "build.sh", []byte(`echo "{{.}}"`), 0644))
+	require.NoError(t, afero.WriteFile(fs, "ci.yml", []byte(`name: <%param "github.repo"%> ${{ github.ref }}`), 0644))
+	require.NoError(t, afero.WriteFile(fs, "invalid.txt", []byte(`{{param "github.repo"}}`), 0644))
+
+	proc := Processor{
+		Attributes: func(path string) Attributes {
+			switch path {
+			case "build.sh":
+				return Attributes{Skip: true}
+			case "ci.yml":
+				return Attributes{LeftDelim: "<%", RightDelim: "%>"}
+			case "invalid.txt":
+				return Attributes{LeftDelim: "<%"}
+			}
+			return Attributes{}
+		},
+
+		srcFS: fs,
+		dstFS: fs,
+	}
+	proc.Initialize()
+
+	err := proc.Execute(".", map[string]string{"github.repo": "template-golang"})
+	assert.EqualError(t, err, "failed to process 1 template")
+
+	want := map[string]string{
+		"README.md":   "# template-golang",
+		"build.sh":    `echo "{{.}}"`,
+		"ci.yml":      "name: template-golang ${{ github.ref }}",
+		"invalid.txt": `{{param "github.repo"}}`,
+	}
+	for path, content := range want {
+		got, err := afero.ReadFile(fs, path)
+		require.NoError(t, err)
+		assert.Equal(t, content, string(got), path)
+	}
+}
+
+func TestProcessor_Execute_format(t *testing.T) {
+	t.Parallel()
+
+	srcFS := afero.NewMemMapFs()
+	require.NoError(t, srcFS.Mkdir("_partials", 0755))
+	require.NoError(t, afero.WriteFile(srcFS, "_partials/header.txt", []byte("\xEF\xBB\xBF# Copyright {{param \"git.name\"}}\n# Licensed under MIT\n"), 0644))
+	require.NoError(t, afero.WriteFile(srcFS, "build.cmd", []byte("@echo off\r\nREM {{param \"github.repo\"}}\r\n{{param \"description\"}}\r\n"), 0644))
+	require.NoError(t, afero.WriteFile(srcFS, "README.md", []byte("\xEF\xBB\xBF# {{param \"github.repo\"}}\n"), 0644))
+	require.NoError(t, afero.WriteFile(srcFS, "build.sh", []byte("#!/bin/sh\n{{template \"header\" .}}echo {{param \"github.repo\"}}\n"), 0755))
+	require.NoError(t, afero.WriteFile(srcFS, "build.ps1", []byte("{{template \"header\" .}}\r\nWrite-Host {{param \"github.repo\"}}\r\n"), 0644))
+	require.NoError(t, afero.WriteFile(srcFS, "mixed.txt", []byte("a\r\n{{if true}}b\r\n{{end}}c {{param \"description\"}}\n"), 0644))
+
+	dstFS := afero.NewMemMapFs()
+	proc := Processor{
+		srcFS: srcFS,
+		dstFS: afero.NewCopyOnWriteFs(srcFS, dstFS),
+	}
+	proc.Initialize()
+
+	params := map[string]string{
+		"description": "Line 1\nLine 2",
+		"git.name":    "Heath Stewart",
+		"github.repo": "template-golang",
+	}
+	err := proc.Execute(".", params)
+	require.NoError(t, err)
+
+	tests := []struct {
+		path    string
+		content string
+		mode    os.FileMode
+	}{
+		{
+			path:    "build.cmd",
+			content: "@echo off\r\nREM template-golang\r\nLine 1\r\nLine 2\r\n",
+			mode:    0644,
+		},
+		{
+			path:    "README.md",
+			content: "\xEF\xBB\xBF# template-golang\n",
+			mode:    0644,
+		},
+		{
+			path:    "build.sh",
+			content: "#!/bin/sh\n# Copyright Heath Stewart\n# Licensed under MIT\necho template-golang\n",
+			mode:    0755,
+		},
+		{
+			path:    "build.ps1",
+			content: "# Copyright Heath Stewart\r\n# Licensed under MIT\r\n\r\nWrite-Host template-golang\r\n",
+			mode:    0644,
+		},
+		{
+			// Mixed line endings in literal text are unchanged, while parameters use the predominant line ending.
+			path:    "mixed.txt",
+			content: "a\r\nb\r\nc Line 1\r\nLine 2\n",
+			mode:    0644,
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := afero.ReadFile(dstFS, tt.path)
+		require.NoError(t, err, tt.path)
+		assert.Equal(t, tt.content, string(got), tt.path)
+
+		info, err := dstFS.Stat(tt.path)
+		require.NoError(t, err, tt.path)
+		assert.Equal(t, tt.mode, info.Mode().Perm(), tt.path)
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		content string
+		want    fileFormat
+		body    string
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:    "no line endings",
+			content: "text",
+			body:    "text",
+		},
+		{
+			name:    "lf",
+			content: "a\nb\n",
+			want:    fileFormat{eol: lf},
+			body:    "a\nb\n",
+		},
+		{
+			name:    "crlf",
+			content: "a\r\nb\r\nc\n",
+			want:    fileFormat{eol: crlf},
+			body:    "a\r\nb\r\nc\n",
+		},
+		{
+			name:    "bom",
+			content: "\xEF\xBB\xBFa\r\n",
+			want:    fileFormat{bom: true, eol: crlf},
+			body:    "a\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, body := detectFormat([]byte(tt.content))
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.body, string(body))
+		})
+	}
+}
+
+func TestProcessor_Execute_parallel(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, fs.MkdirAll("_partials", 0755))
+	require.NoError(t, afero.WriteFile(fs, "_partials/header.md", []byte(`# {{param "second" "" "Second"}}`), 0644))
+	require.NoError(t, afero.WriteFile(fs, "a.md", []byte(`{{param "first" "" "First"}}`), 0644))
+	require.NoError(t, afero.WriteFile(fs, "b.md", []byte(`{{template "header" .}}`), 0644))
+	require.NoError(t, afero.WriteFile(fs, "c.md", []byte(`{{if eq (param "first") "1"}}{{param "third" "" "Third"}}{{end}}`), 0644))
+	for i := 0; i < 100; i++ {
+		name := fmt.Sprintf("files/%03d.md", i)
+		require.NoError(t, afero.WriteFile(fs, name, []byte(`{{param "first"}} {{param "second"}} {{deleteFile "`+name+`.bak"}}`), 0644))
+		require.NoError(t, afero.WriteFile(fs, name+".bak", []byte(`backup`), 0644))
+	}
+
+	con := console.Fake(
+		console.WithStdin(bytes.NewBufferString("1\n2\n3\n")),
+		console.WithStderrTTY(true),
+	)
+	proc := Processor{
+		Stderr:  con.Stderr(),
+		Stdin:   iotest.OneByteReader(con.Stdin()),
+		IsTTY:   con.IsStderrTTY(),
+		Workers: 4,
+
+		srcFS: fs,
+		dstFS: fs,
+	}
+	proc.Initialize()
+
+	params := map[string]string{}
+	err := proc.Execute(".", params)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"first": "1", "second": "2", "third": "3"}, params)
+
+	_, stderr, _ := con.Buffers()
+	prompts := stderr.String()
+	assert.Equal(t, 1, strings.Count(prompts, "First (first)"))
+	assert.Equal(t, 1, strings.Count(prompts, "Second (second)"))
+	assert.Equal(t, 1, strings.Count(prompts, "Third (third)"))
+	assert.Less(t, strings.Index(prompts, "First"), strings.Index(prompts, "Second"))
+	assert.Less(t, strings.Index(prompts, "Second"), strings.Index(prompts, "Third"))
+
+	for i := 0; i < 100; i++ {
+		name := fmt.Sprintf("files/%03d.md", i)
+		got, err := afero.ReadFile(fs, name)
+		require.NoError(t, err)
+		assert.Equal(t, "1 2 ", string(got), name)
+
+		_, err = fs.Stat(name + ".bak")
+		assert.Error(t, err, "%q should not exist", name+".bak")
+	}
+}
+
+func TestCallsFunc(t *testing.T) {
+	t.Parallel()
+
+	funcs := template.FuncMap{"param": func(string) string { return "" }}
+	tests := []struct {
+		name    string
+		content string
+		want    bool
+	}{
+		{name: "text", content: "text"},
+		{name: "action", content: `{{param "a"}}`, want: true},
+		{name: "pipeline", content: `{{"a" | printf "%s"}}`},
+		{name: "nested", content: `{{printf "%s" (param "a")}}`, want: true},
+		{name: "if", content: `{{if true}}{{else}}{{param "a"}}{{end}}`, want: true},
+		{name: "range", content: `{{range $i := .}}{{$i}}{{end}}`},
+		{name: "with", content: `{{with $a := param "a"}}{{$a}}{{end}}`, want: true},
+		{name: "partial", content: `{{define "p"}}{{param "a"}}{{end}}{{template "p" .}}`, want: true},
+		{name: "recursive", content: `{{define "p"}}{{template "p" .}}{{end}}{{template "p" .}}`},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tmpl, err := template.New(tt.name).Funcs(funcs).Parse(tt.content)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, callsFunc(tmpl, tmpl.Tree, "param", make(map[string]bool)))
+		})
+	}
+}
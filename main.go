@@ -4,8 +4,10 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	"os/signal"
 
 	"github.com/cli/go-gh/pkg/repository"
 	"github.com/heaths/gh-template/internal/cmd"
@@ -16,10 +18,18 @@ import (
 func main() {
 	con := console.System()
 
+	// Commands stop and restore any changed files when first interrupted; interrupt again to exit immediately.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
 	var repo string
 	opts := &cmd.GlobalOptions{
 		Console: con,
 		Log:     log.New(con.Stdout(), "", log.Ltime),
+		Context: ctx,
 	}
 
 	rootCmd := &cobra.Command{
@@ -50,10 +60,14 @@ func main() {
 	rootCmd.PersistentFlags().BoolVarP(&opts.Verbose, "verbose", "v", false, "Log verbose output")
 
 	rootCmd.AddCommand(cmd.ApplyCmd(opts))
+	rootCmd.AddCommand(cmd.BatchCmd(opts))
 	rootCmd.AddCommand(cmd.CloneCmd(opts))
 	rootCmd.AddCommand(cmd.ListCmd(opts))
 
 	if err := rootCmd.Execute(); err != nil {
+		if ctx.Err() != nil {
+			os.Exit(130)
+		}
 		os.Exit(1)
 	}
 }